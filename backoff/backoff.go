@@ -92,6 +92,9 @@ type Clock interface {
 	Now() time.Time
 }
 
+// Stop indicates that no more retries should be made.
+const Stop time.Duration = -1
+
 // Default values for ExponentialBackOff.
 const (
 	DefaultInitialInterval     = 1000 * time.Millisecond
@@ -130,9 +133,14 @@ func (b *ExponentialBackOff) Reset() {
 
 // NextBackOff calculates the next backoff interval using the formula:
 // 	Randomized interval = RetryInterval +/- (RandomizationFactor * RetryInterval)
+// It returns Stop once waiting any longer would exceed MaxElapsedTime.
 func (b *ExponentialBackOff) NextBackOff() time.Duration {
 	defer b.incrementCurrentInterval()
-	return getRandomValueFromInterval(b.RandomizationFactor, rand.Float64(), b.currentInterval)
+	next := getRandomValueFromInterval(b.RandomizationFactor, rand.Float64(), b.currentInterval)
+	if b.MaxElapsedTime != 0 && b.GetElapsedTime()+next > b.MaxElapsedTime {
+		return Stop
+	}
+	return next
 }
 
 // GetElapsedTime returns the elapsed time since an ExponentialBackOff instance
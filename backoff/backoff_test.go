@@ -93,6 +93,16 @@ func TestGetElapsedTime(t *testing.T) {
 	}
 }
 
+func TestMaxElapsedTime(t *testing.T) {
+	var exp = NewExponentialBackOff()
+	exp.Clock = &TestClock{start: time.Time{}.Add(10000 * time.Second)}
+	// Change the currentElapsedTime to be 0 ensuring that the elapsed time will be greater
+	// than the max elapsed time.
+	exp.startTime = time.Time{}
+	exp.MaxElapsedTime = 1000 * time.Second
+	assertEquals(t, Stop, exp.NextBackOff())
+}
+
 func TestBackOffOverflow(t *testing.T) {
 	var (
 		testInitialInterval time.Duration = math.MaxInt64 / 2
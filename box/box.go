@@ -26,24 +26,13 @@ import (
 	"time"
 
 	"github.com/cypherpunkarmory/ulacli/backoff"
-	"github.com/fatih/color"
+	"github.com/cypherpunkarmory/ulacli/proxy"
 	"github.com/shiena/ansicolor"
 	log "github.com/sirupsen/logrus"
-	"github.com/tj/go-spin"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
-type boxStatus struct {
-	state string
-}
-
-const (
-	boxError    string = "error"
-	boxDone     string = "done"
-	boxStarting string = "starting"
-)
-
 //StartBox Main box function. Handles connections and forwarding
 func StartBox(boxConfig *Config, wg *sync.WaitGroup, semaphore *Semaphore) {
 	defer cleanup(boxConfig)
@@ -51,25 +40,56 @@ func StartBox(boxConfig *Config, wg *sync.WaitGroup, semaphore *Semaphore) {
 	if wg != nil {
 		defer wg.Done()
 	}
-	client, err := createBox(boxConfig, semaphore)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		return
+	if boxConfig.Progress == nil {
+		boxConfig.Progress = NewProgress(false, false)
 	}
+	progress := boxConfig.Progress
 
-	defer client.Close()
-
-	// This catches CTRL C and closes the ssh
-	startCloseChannel := make(chan os.Signal)
-	signal.Notify(startCloseChannel,
+	// This catches CTRL C from the moment the box exists until the shell exits, so
+	// an interrupted connect or provisioning script doesn't leave the box running
+	closeChannel := make(chan os.Signal, 1)
+	signal.Notify(closeChannel,
 		// https://www.gnu.org/software/libc/manual/html_node/Termination-Signals.html
 		syscall.SIGTERM, // "the normal way to politely ask a program to terminate"
 		syscall.SIGINT,  // Ctrl+C
 		syscall.SIGQUIT, // Ctrl-\
 		syscall.SIGHUP,  // "terminal is disconnected"
 	)
+	defer signal.Stop(closeChannel)
+	go func() {
+		<-closeChannel
+		log.Debugf("Closing box")
+		progress.Fail()
+		if semaphore.CanRun() {
+			cleanup(boxConfig)
+			os.Exit(0)
+		}
+	}()
+
+	client, err := createBox(boxConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return
+	}
+
+	defer client.Close()
+
+	err = provision(client, boxConfig.Provision, boxConfig.Env, progress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		if !semaphore.CanRun() {
+			// Interrupted, the signal handler deletes the box and exits
+			select {}
+		}
+		// Delete the box ourselves since os.Exit skips the deferred cleanup
+		cleanup(boxConfig)
+		os.Exit(err.(*ProvisionError).ExitStatus)
+	}
+
+	progress.Start("Opening shell")
 	session, err := client.NewSession()
 	if err != nil {
+		progress.Fail()
 		panic("Failed to create session: " + err.Error())
 	}
 	defer session.Close()
@@ -79,6 +99,16 @@ func StartBox(boxConfig *Config, wg *sync.WaitGroup, semaphore *Semaphore) {
 	session.Stderr = ansicolor.NewAnsiColorWriter(os.Stderr)
 	session.Stdin = os.Stdin
 
+	sessionDone := make(chan struct{})
+	defer close(sessionDone)
+	if boxConfig.IdleTimeout > 0 {
+		monitor := newIdleMonitor(boxConfig.IdleTimeout)
+		session.Stdout = activityWriter{session.Stdout, monitor}
+		session.Stderr = activityWriter{session.Stderr, monitor}
+		session.Stdin = activityReader{session.Stdin, monitor}
+		go closeWhenIdle(monitor, client, sessionDone)
+	}
+
 	// Set up terminal modes
 	// https://net-ssh.github.io/net-ssh/classes/Net/SSH/Connection/Term.html
 	// https://www.ietf.org/rfc/rfc4254.txt
@@ -92,64 +122,79 @@ func StartBox(boxConfig *Config, wg *sync.WaitGroup, semaphore *Semaphore) {
 	}
 
 	fileDescriptor := int(os.Stdin.Fd())
+	isTerminal := terminal.IsTerminal(fileDescriptor)
 
-	if terminal.IsTerminal(fileDescriptor) {
-		originalState, err := terminal.MakeRaw(fileDescriptor)
-		if err != nil {
-			log.Fatalf("Unable to put the terminal connected to a file descriptor into raw mode: %v", err)
-		}
-		defer terminal.Restore(fileDescriptor, originalState)
-
+	if isTerminal {
 		termWidth, termHeight, err := terminal.GetSize(fileDescriptor)
 		if err != nil {
+			progress.Fail()
 			log.Fatalf("Unable to get the dimensions for the terminal: %v", err)
 		}
 
 		err = session.RequestPty("xterm-256color", termHeight, termWidth, modes)
 		if err != nil {
+			progress.Fail()
 			log.Fatalf("Unable to request pty for the session: %v", err)
 		}
 	}
 
-	// Start remote shell
-	if err := session.Shell(); err != nil {
+	// Start remote shell, exporting any variables the server refused ourselves
+	rejected := setEnv(session, boxConfig.Env)
+	if len(rejected) == 0 {
+		err = session.Shell()
+	} else {
+		err = session.Start(shellWithEnv(rejected))
+	}
+	if err != nil {
+		progress.Fail()
 		log.Fatalf("failed to start shell: %s", err)
 	}
+	progress.Done()
+	progress.Summary()
 
-	go func() {
-		<-startCloseChannel
-		if semaphore.CanRun() {
-			cleanup(boxConfig)
-			os.Exit(0)
+	// Raw mode comes last so the progress output above still gets normal line endings
+	if isTerminal {
+		originalState, err := terminal.MakeRaw(fileDescriptor)
+		if err != nil {
+			log.Fatalf("Unable to put the terminal connected to a file descriptor into raw mode: %v", err)
 		}
-	}()
+		defer terminal.Restore(fileDescriptor, originalState)
+	}
 
 	// Accepting commands
 	session.Wait()
 }
 
-func createBox(boxConfig *Config, semaphore *Semaphore) (*ssh.Client, error) {
-	boxCreating := boxStatus{boxStarting}
-	createCloseChannel := make(chan os.Signal)
-	signal.Notify(createCloseChannel,
-		// https://www.gnu.org/software/libc/manual/html_node/Termination-Signals.html
-		syscall.SIGTERM, // "the normal way to politely ask a program to terminate"
-		syscall.SIGINT,  // Ctrl+C
-		syscall.SIGQUIT, // Ctrl-\
-		syscall.SIGHUP,  // "terminal is disconnected"
-	)
-	defer signal.Stop(createCloseChannel)
-	go func() {
-		<-createCloseChannel
-		log.Debugf("Closing box")
-		boxCreating.state = boxError
-		for !semaphore.CanRun() {
+// closeWhenIdle shuts the session down once nothing has gone through it for the idle timeout.
+// session.Wait then returns and StartBox deletes the box the same way it does on a normal exit.
+func closeWhenIdle(monitor *idleMonitor, client *ssh.Client, done <-chan struct{}) {
+	warn := func(remaining time.Duration) {
+		fmt.Fprintf(os.Stderr, "\r\nThis box has been idle and will shut down in %s unless you use it\r\n",
+			remaining.Round(time.Second))
+	}
+	if !monitor.watch(warn, done) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r\nThis box was idle for %s, shutting it down\r\n", monitor.timeout)
+	client.Close()
+}
 
-		}
-		defer semaphore.Done()
-		cleanup(boxConfig)
-		os.Exit(0)
-	}()
+// dialJump goes through the configured proxy, ssh.Dial can only connect directly
+func dialJump(boxConfig *Config, addr string, sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := proxy.Dial(boxConfig.Proxy, addr, sshConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func createBox(boxConfig *Config) (*ssh.Client, error) {
+	progress := boxConfig.Progress
 	lvl, err := log.ParseLevel(boxConfig.LogLevel)
 	if err != nil {
 		log.Errorf("\nLog level %s is not a valid level.", boxConfig.LogLevel)
@@ -193,16 +238,18 @@ func createBox(boxConfig *Config, semaphore *Semaphore) (*ssh.Client, error) {
 		Timeout:         0,
 	}
 
+	progress.Start("Connecting to UserLAnd server")
 	log.Debugf("Dial into Jump Server %s", jumpServerEndpoint.String())
-	jumpConn, err := ssh.Dial("tcp", jumpServerEndpoint.String(), sshJumpConfig)
+	jumpConn, err := dialJump(boxConfig, jumpServerEndpoint.String(), sshJumpConfig)
 
 	if err != nil {
+		progress.Fail()
 		fmt.Fprintf(os.Stderr, "Error contacting the UserLAnd server.")
 		log.Debugf("%s", err)
 		return &client, err
 	}
 
-	boxStartingSpinner(semaphore, &boxCreating)
+	progress.Start("Waiting for box to boot")
 	exponentialBackoff := backoff.NewExponentialBackOff()
 
 	// Connect to SSH remote server using serverEndpoint
@@ -211,7 +258,6 @@ func createBox(boxConfig *Config, semaphore *Semaphore) (*ssh.Client, error) {
 		serverConn, err = jumpConn.Dial("tcp", serverEndpoint.String())
 		log.Debugf("Dial into SSHD Container %s", serverEndpoint.String())
 		if err == nil {
-			boxCreating.state = boxDone
 			break
 		}
 		wait := exponentialBackoff.NextBackOff()
@@ -228,10 +274,13 @@ func createBox(boxConfig *Config, semaphore *Semaphore) (*ssh.Client, error) {
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 		Timeout:         0,
 	}
+	progress.Start("Authenticating")
 	ncc, chans, reqs, err := ssh.NewClientConn(serverConn, serverEndpoint.String(), sshBoxConfig)
 	if err != nil {
+		progress.Fail()
 		return &client, err
 	}
+	progress.Done()
 	log.Debugf("SSH Connection Established via Jump %s -> %s", jumpServerEndpoint.String(), serverEndpoint.String())
 
 	sClient := ssh.NewClient(ncc, chans, reqs)
@@ -239,32 +288,20 @@ func createBox(boxConfig *Config, semaphore *Semaphore) (*ssh.Client, error) {
 	return sClient, nil
 }
 
-func boxStartingSpinner(lock *Semaphore, boxStatus *boxStatus) {
-	go func() {
-		if !lock.CanRun() {
-			return
-		}
-		defer lock.Done()
-		s := spin.New()
-		for boxStatus.state == boxStarting {
-			fmt.Printf("\rStarting box %s ", s.Next())
-			time.Sleep(100 * time.Millisecond)
-		}
-		if boxStatus.state == boxDone {
-			fmt.Printf("\rStarting box ")
-			d := color.New(color.FgGreen, color.Bold)
-			d.Printf("✔\n")
-		}
-	}()
-}
 func cleanup(config *Config) {
 	fmt.Println("\nClosing box")
-	config.RestAPI.SetRefreshToken(config.RestAPI.RefreshToken)
-	errSession := config.RestAPI.StartSession(config.RestAPI.RefreshToken)
-	config.RestAPI.SetAPIKey(config.RestAPI.APIKey)
+	// The access token may have expired during a long session, RestClient refreshes it if needed
 	errDelete := config.RestAPI.DeleteBoxAPI(config.Box.ID)
-	if errSession != nil || errDelete != nil {
+	if errDelete != nil {
 		fmt.Fprintf(os.Stderr,
-			"We had some trouble deleting your box\n")
+			"We had some trouble deleting your box\n"+
+				"Run `ulacli gc` to try again\n")
+		return
+	}
+	if config.StatePath != "" {
+		err := RemoveRecord(config.StatePath, config.Box.ID)
+		if err != nil {
+			log.Debugf("Couldn't update box state file: %s", err)
+		}
 	}
 }
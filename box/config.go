@@ -19,7 +19,9 @@ package box
 import (
 	"fmt"
 	"net/url"
+	"time"
 
+	"github.com/cypherpunkarmory/ulacli/proxy"
 	"github.com/cypherpunkarmory/ulacli/restapi"
 )
 
@@ -27,10 +29,16 @@ import (
 type Config struct {
 	ConnectionEndpoint url.URL
 	RestAPI            restapi.RestClient
-	Box		           restapi.Box
+	Box                restapi.Box
 	PrivateKeyPath     string
 	LocalPort          string
 	LogLevel           string
+	Progress           *Progress
+	StatePath          string
+	IdleTimeout        time.Duration
+	Provision          []string
+	Env                []string
+	Proxy              proxy.Func
 }
 
 type Endpoint struct {
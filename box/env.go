@@ -0,0 +1,74 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package box
+
+import (
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// Names are written into shell commands, so nothing but a plain identifier may get through
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+//ValidEnvName Reports whether name can be used as an environment variable in the box
+func ValidEnvName(name string) bool {
+	return envName.MatchString(name)
+}
+
+// setEnv sends every KEY=VALUE pair as an env request and returns the ones
+// the server refused. Most sshd configs only accept a handful of names.
+func setEnv(session *ssh.Session, env []string) []string {
+	var rejected []string
+	for _, kv := range env {
+		parts := strings.SplitN(kv, "=", 2)
+		err := session.Setenv(parts[0], parts[1])
+		if err != nil {
+			log.Debugf("Server refused env %s, exporting it from the shell instead", parts[0])
+			rejected = append(rejected, kv)
+		}
+	}
+	return rejected
+}
+
+// exportEnv builds a shell statement exporting every KEY=VALUE pair
+func exportEnv(env []string) string {
+	if len(env) == 0 {
+		return ""
+	}
+	exports := make([]string, 0, len(env))
+	for _, kv := range env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || !ValidEnvName(parts[0]) {
+			log.Debugf("Not exporting invalid env %s", parts[0])
+			continue
+		}
+		exports = append(exports, parts[0]+"="+shellQuote(parts[1]))
+	}
+	if len(exports) == 0 {
+		return ""
+	}
+	return "export " + strings.Join(exports, " ") + "; "
+}
+
+// shellWithEnv is the command used in place of a plain shell request when the
+// server refused some variables: export them and replace itself with a login shell
+func shellWithEnv(env []string) string {
+	return exportEnv(env) + `exec "${SHELL:-/bin/sh}" -l`
+}
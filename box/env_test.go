@@ -0,0 +1,54 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package box
+
+import "testing"
+
+func TestExportEnv(t *testing.T) {
+	cases := []struct {
+		name     string
+		env      []string
+		expected string
+	}{
+		{"Nothing", nil, ""},
+		{"Quoted values", []string{"A=it's", "B=$(id)"}, `export A='it'\''s' B='$(id)'; `},
+		{"Command separator", []string{"A;id;B=x"}, ""},
+		{"Command substitution", []string{"$(id)=x", "OK=1"}, `export OK='1'; `},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := exportEnv(tc.env); actual != tc.expected {
+				t.Fatalf("Expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidEnvName(t *testing.T) {
+	for _, name := range []string{"PATH", "_x", "LC_ALL", "a1"} {
+		if !ValidEnvName(name) {
+			t.Errorf("%s should be valid", name)
+		}
+	}
+	for _, name := range []string{"", "1A", "A;id;B", "$(id)", "A B", "A=B", "A-B", "`id`"} {
+		if ValidEnvName(name) {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package box
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+const maxIdleWarning = time.Minute
+
+// idleMonitor records the last time anything was typed into or printed by the session
+type idleMonitor struct {
+	timeout  time.Duration
+	warning  time.Duration
+	interval time.Duration
+	last     int64
+}
+
+func newIdleMonitor(timeout time.Duration) *idleMonitor {
+	warning := timeout / 5
+	if warning > maxIdleWarning {
+		warning = maxIdleWarning
+	}
+	interval := time.Second
+	if timeout/10 < interval {
+		interval = timeout / 10
+	}
+	m := &idleMonitor{timeout: timeout, warning: warning, interval: interval}
+	m.touch()
+	return m
+}
+
+func (m *idleMonitor) touch() {
+	atomic.StoreInt64(&m.last, time.Now().UnixNano())
+}
+
+func (m *idleMonitor) idle() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&m.last)))
+}
+
+// watch blocks until the session has been idle for the whole timeout and returns true.
+// warn is called once each time the session gets close to the timeout.
+// It returns false if done is closed first.
+func (m *idleMonitor) watch(warn func(remaining time.Duration), done <-chan struct{}) bool {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	warned := false
+	for {
+		select {
+		case <-done:
+			return false
+		case <-ticker.C:
+		}
+		idle := m.idle()
+		switch {
+		case idle >= m.timeout:
+			return true
+		case idle >= m.timeout-m.warning:
+			if !warned {
+				warn(m.timeout - idle)
+				warned = true
+			}
+		default:
+			warned = false
+		}
+	}
+}
+
+type activityReader struct {
+	r io.Reader
+	m *idleMonitor
+}
+
+func (a activityReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if n > 0 {
+		a.m.touch()
+	}
+	return n, err
+}
+
+type activityWriter struct {
+	w io.Writer
+	m *idleMonitor
+}
+
+func (a activityWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		a.m.touch()
+	}
+	return a.w.Write(p)
+}
@@ -0,0 +1,53 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package box
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestIdleMonitorFires(t *testing.T) {
+	monitor := newIdleMonitor(100 * time.Millisecond)
+	warnings := 0
+	fired := monitor.watch(func(time.Duration) { warnings++ }, make(chan struct{}))
+	if !fired {
+		t.Fatal("Expected the idle timeout to fire")
+	}
+	if warnings != 1 {
+		t.Fatalf("Expected one warning, got %d", warnings)
+	}
+}
+
+func TestIdleMonitorActivity(t *testing.T) {
+	monitor := newIdleMonitor(100 * time.Millisecond)
+	writer := activityWriter{&bytes.Buffer{}, monitor}
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			time.Sleep(30 * time.Millisecond)
+			writer.Write([]byte("x"))
+		}
+		close(done)
+	}()
+	if monitor.watch(func(time.Duration) {}, done) {
+		t.Fatal("Session with traffic should not be idle")
+	}
+}
@@ -0,0 +1,183 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package box
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/tj/go-spin"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+//Progress Reports each phase of starting a box along with how long it took.
+//On a terminal the current phase gets a spinner, otherwise every phase is
+//written as plain lines so CI logs stay readable.
+type Progress struct {
+	out     io.Writer
+	tty     bool
+	quiet   bool
+	timings bool
+
+	mu      sync.Mutex
+	phase   string
+	started time.Time
+	halted  bool
+	stop    chan struct{}
+	stopped chan struct{}
+	phases  []phaseTiming
+}
+
+type phaseTiming struct {
+	name    string
+	elapsed time.Duration
+}
+
+//NewProgress Creates a progress reporter writing to stdout
+func NewProgress(quiet bool, timings bool) *Progress {
+	return &Progress{
+		out:     os.Stdout,
+		tty:     terminal.IsTerminal(int(os.Stdout.Fd())),
+		quiet:   quiet,
+		timings: timings,
+	}
+}
+
+//Start Begins a new phase, finishing the previous one if it is still running
+func (p *Progress) Start(phase string) {
+	p.start(phase, true)
+}
+
+//StartWithOutput Begins a phase that prints output of its own, so it gets a
+//plain header line instead of a spinner
+func (p *Progress) StartWithOutput(phase string) {
+	p.start(phase, false)
+}
+
+func (p *Progress) start(phase string, spinner bool) {
+	p.Done()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.halted {
+		return
+	}
+	p.phase = phase
+	p.started = time.Now()
+	if p.quiet {
+		return
+	}
+	if !p.tty || !spinner {
+		fmt.Fprintf(p.out, "%s...\n", phase)
+		return
+	}
+	p.stop = make(chan struct{})
+	p.stopped = make(chan struct{})
+	go p.spin(phase, p.stop, p.stopped)
+}
+
+//Done Marks the current phase as finished
+func (p *Progress) Done() {
+	p.finish(true)
+}
+
+//Fail Marks the current phase as failed and stops reporting any further phases
+func (p *Progress) Fail() {
+	p.finish(false)
+}
+
+//Summary Prints the time taken by every finished phase when timings were requested
+func (p *Progress) Summary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.timings || len(p.phases) == 0 {
+		return
+	}
+	var total time.Duration
+	fmt.Fprintln(p.out, "Startup timings:")
+	for _, t := range p.phases {
+		fmt.Fprintf(p.out, "  %-28s %s\n", t.name, formatElapsed(t.elapsed))
+		total += t.elapsed
+	}
+	fmt.Fprintf(p.out, "  %-28s %s\n", "Total", formatElapsed(total))
+}
+
+func (p *Progress) finish(ok bool) {
+	p.mu.Lock()
+	if !ok {
+		p.halted = true
+	}
+	phase := p.phase
+	if phase == "" {
+		p.mu.Unlock()
+		return
+	}
+	elapsed := time.Since(p.started)
+	stop, stopped := p.stop, p.stopped
+	p.phase, p.stop, p.stopped = "", nil, nil
+	if ok {
+		p.phases = append(p.phases, phaseTiming{phase, elapsed})
+	}
+	p.mu.Unlock()
+
+	// Wait for the spinner so it can't overwrite the final line
+	if stop != nil {
+		close(stop)
+		<-stopped
+	}
+	if p.quiet {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.tty && ok:
+		fmt.Fprintf(p.out, "\r%s %s %s\n", phase, color.New(color.FgGreen, color.Bold).Sprint("✔"), formatElapsed(elapsed))
+	case p.tty:
+		fmt.Fprintf(p.out, "\r%s %s %s\n", phase, color.New(color.FgRed, color.Bold).Sprint("✘"), formatElapsed(elapsed))
+	case ok:
+		fmt.Fprintf(p.out, "%s done (%s)\n", phase, formatElapsed(elapsed))
+	default:
+		fmt.Fprintf(p.out, "%s failed (%s)\n", phase, formatElapsed(elapsed))
+	}
+}
+
+func (p *Progress) spin(phase string, stop chan struct{}, stopped chan struct{}) {
+	defer close(stopped)
+	s := spin.New()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		p.mu.Lock()
+		fmt.Fprintf(p.out, "\r%s %s ", phase, s.Next())
+		p.mu.Unlock()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func formatElapsed(d time.Duration) string {
+	return d.Truncate(10 * time.Millisecond).String()
+}
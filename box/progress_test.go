@@ -0,0 +1,88 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package box
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestProgressPlainOutput(t *testing.T) {
+	var out bytes.Buffer
+	p := &Progress{out: &out, timings: true}
+	p.Start("Creating box")
+	p.StartWithOutput("Running provisioning scripts")
+	p.Done()
+	p.Summary()
+
+	expected := regexp.MustCompile(`^Creating box\.\.\.
+Creating box done \(\d+(\.\d+)?[mµn]?s\)
+Running provisioning scripts\.\.\.
+Running provisioning scripts done \(\d+(\.\d+)?[mµn]?s\)
+Startup timings:
+  Creating box +\d+(\.\d+)?[mµn]?s
+  Running provisioning scripts +\d+(\.\d+)?[mµn]?s
+  Total +\d+(\.\d+)?[mµn]?s
+$`)
+	if !expected.MatchString(out.String()) {
+		t.Fatalf("Unexpected output:\n%s", out.String())
+	}
+}
+
+func TestProgressFail(t *testing.T) {
+	var out bytes.Buffer
+	p := &Progress{out: &out, timings: true}
+	p.Start("Creating box")
+	p.Fail()
+	p.Start("Dialing jump host")
+	p.Done()
+	p.Summary()
+
+	expected := regexp.MustCompile(`^Creating box\.\.\.
+Creating box failed \(\d+(\.\d+)?[mµn]?s\)
+$`)
+	if !expected.MatchString(out.String()) {
+		t.Fatalf("Unexpected output:\n%s", out.String())
+	}
+}
+
+func TestProgressQuiet(t *testing.T) {
+	var out bytes.Buffer
+	p := &Progress{out: &out, quiet: true}
+	p.Start("Creating box")
+	p.Done()
+	p.Summary()
+	if out.Len() != 0 {
+		t.Fatalf("Expected no output, got:\n%s", out.String())
+	}
+}
+
+func TestProgressTerminal(t *testing.T) {
+	var out bytes.Buffer
+	p := &Progress{out: &out, tty: true}
+	p.Start("Creating box")
+	p.Done()
+
+	// The spinner redraws the phase in place until the final line replaces it
+	expected := regexp.MustCompile(`^(\rCreating box \S )+\rCreating box ✔ \d+(\.\d+)?[mµn]?s\n$`)
+	if !expected.MatchString(out.String()) {
+		t.Fatalf("Unexpected output: %q", out.String())
+	}
+}
@@ -0,0 +1,100 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package box
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shiena/ansicolor"
+	"golang.org/x/crypto/ssh"
+)
+
+//ProvisionError A provisioning script could not be run or exited with a non-zero status
+type ProvisionError struct {
+	Script     string
+	ExitStatus int
+	Err        error
+}
+
+func (e *ProvisionError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("provisioning script %s failed: %s", e.Script, e.Err)
+	}
+	return fmt.Sprintf("provisioning script %s exited with status %d", e.Script, e.ExitStatus)
+}
+
+// provision uploads each script to the box and runs it there with env exported,
+// streaming its output. It stops at the first script that fails.
+func provision(client *ssh.Client, scripts []string, env []string, progress *Progress) error {
+	for i, script := range scripts {
+		name := filepath.Base(script)
+		remotePath := fmt.Sprintf("/tmp/ulacli-provision-%d-%s", i, name)
+
+		progress.StartWithOutput("Provisioning " + name)
+		err := uploadScript(client, script, remotePath)
+		if err != nil {
+			progress.Fail()
+			return &ProvisionError{Script: script, ExitStatus: 1, Err: err}
+		}
+
+		session, err := client.NewSession()
+		if err != nil {
+			progress.Fail()
+			return &ProvisionError{Script: script, ExitStatus: 1, Err: err}
+		}
+		session.Stdout = ansicolor.NewAnsiColorWriter(os.Stdout)
+		session.Stderr = ansicolor.NewAnsiColorWriter(os.Stderr)
+		quoted := shellQuote(remotePath)
+		err = session.Run(exportEnv(env) + quoted + "; status=$?; rm -f " + quoted + "; exit $status")
+		session.Close()
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			progress.Fail()
+			return &ProvisionError{Script: script, ExitStatus: exitErr.ExitStatus()}
+		}
+		if err != nil {
+			progress.Fail()
+			return &ProvisionError{Script: script, ExitStatus: 1, Err: err}
+		}
+		progress.Done()
+	}
+	return nil
+}
+
+func uploadScript(client *ssh.Client, localPath string, remotePath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	session.Stdin = file
+	quoted := shellQuote(remotePath)
+	return session.Run("cat > " + quoted + " && chmod 700 " + quoted)
+}
+
+// shellQuote wraps s in single quotes so a POSIX shell takes it literally
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,212 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package box
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type execRequest struct {
+	command string
+	stdin   string
+}
+
+// testSSHClient connects to a loopback SSH server that answers every exec
+// request with the exit status run returns
+func testSSHClient(t *testing.T, run func(command string) int) (*ssh.Client, func() []execRequest) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+
+	var mu sync.Mutex
+	var execs []execRequest
+	// net.Pipe won't do, both ends send their version string before reading
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		serverSide, err := listener.Accept()
+		listener.Close()
+		if err != nil {
+			return
+		}
+		_, chans, reqs, err := ssh.NewServerConn(serverSide, serverConfig)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for newChannel := range chans {
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer channel.Close()
+				for req := range requests {
+					if req.Type != "exec" {
+						req.Reply(false, nil)
+						continue
+					}
+					var payload struct{ Command string }
+					ssh.Unmarshal(req.Payload, &payload)
+					req.Reply(true, nil)
+					stdin, _ := ioutil.ReadAll(channel)
+					mu.Lock()
+					execs = append(execs, execRequest{payload.Command, string(stdin)})
+					mu.Unlock()
+					status := struct{ Status uint32 }{uint32(run(payload.Command))}
+					channel.SendRequest("exit-status", false, ssh.Marshal(&status))
+					return
+				}
+			}()
+		}
+	}()
+
+	clientSide, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, chans, reqs, err := ssh.NewClientConn(clientSide, "box", &ssh.ClientConfig{
+		User:            "userland",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ssh.NewClient(conn, chans, reqs), func() []execRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return execs
+	}
+}
+
+func writeScripts(t *testing.T, dir string, names ...string) []string {
+	var paths []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte("echo "+name+"\n"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestProvision(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulacli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	scripts := writeScripts(t, dir, "first.sh", "it's.sh")
+
+	client, execs := testSSHClient(t, func(string) int { return 0 })
+	defer client.Close()
+	err = provision(client, scripts, []string{"EDITOR=vim"}, &Progress{out: ioutil.Discard, quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []execRequest{
+		{`cat > '/tmp/ulacli-provision-0-first.sh' && chmod 700 '/tmp/ulacli-provision-0-first.sh'`, "echo first.sh\n"},
+		{`export EDITOR='vim'; '/tmp/ulacli-provision-0-first.sh'; status=$?; rm -f '/tmp/ulacli-provision-0-first.sh'; exit $status`, ""},
+		{`cat > '/tmp/ulacli-provision-1-it'\''s.sh' && chmod 700 '/tmp/ulacli-provision-1-it'\''s.sh'`, "echo it's.sh\n"},
+		{`export EDITOR='vim'; '/tmp/ulacli-provision-1-it'\''s.sh'; status=$?; rm -f '/tmp/ulacli-provision-1-it'\''s.sh'; exit $status`, ""},
+	}
+	actual := execs()
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d commands, got %+v", len(expected), actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], actual[i])
+		}
+	}
+}
+
+func TestProvisionStopsAtFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulacli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	scripts := writeScripts(t, dir, "first.sh", "second.sh", "third.sh")
+
+	client, execs := testSSHClient(t, func(command string) int {
+		if strings.HasPrefix(command, "'/tmp/ulacli-provision-1-second.sh'") {
+			return 3
+		}
+		return 0
+	})
+	defer client.Close()
+	err = provision(client, scripts, nil, &Progress{out: ioutil.Discard, quiet: true})
+	provisionErr, ok := err.(*ProvisionError)
+	if !ok || provisionErr.Script != scripts[1] || provisionErr.ExitStatus != 3 {
+		t.Fatalf("Expected the second script to fail with status 3, got %v", err)
+	}
+	for _, e := range execs() {
+		if strings.Contains(e.command, "third.sh") {
+			t.Fatal("The third script should not run after the second failed")
+		}
+	}
+
+	err = provision(client, []string{filepath.Join(dir, "missing.sh")}, nil, &Progress{out: ioutil.Discard, quiet: true})
+	provisionErr, ok = err.(*ProvisionError)
+	if !ok || provisionErr.ExitStatus != 1 || provisionErr.Err == nil {
+		t.Fatalf("Expected a missing script to fail with status 1, got %v", err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+	}{
+		{"plain", `'plain'`},
+		{"", `''`},
+		{"with space", `'with space'`},
+		{"it's", `'it'\''s'`},
+		{"$(id); `id`", "'$(id); `id`'"},
+	}
+	for _, tc := range cases {
+		if actual := shellQuote(tc.in); actual != tc.expected {
+			t.Errorf("shellQuote(%q) = %s, expected %s", tc.in, actual, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package box
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// The lock is only held while the file is rewritten, so waiting longer than
+// this means something is wrong
+const lockTimeout = 5 * time.Second
+
+// A lock without a readable PID this old was left behind by a crash
+const staleLockAge = 10 * time.Second
+
+var errStateLocked = errors.New("the box state file is locked by another ulacli process")
+
+//Record A box created from this machine that has not been deleted yet
+type Record struct {
+	ID        string    `json:"id"`
+	Image     string    `json:"image"`
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+//NewRecord Creates a record for a box owned by the current process
+func NewRecord(id string, image string) Record {
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	return Record{
+		ID:        id,
+		Image:     image,
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+//Attached Reports whether the ulacli process that created the box is still running.
+//Boxes created on another host are always treated as attached since we can't tell.
+func (r Record) Attached() bool {
+	hostname, _ := os.Hostname()
+	if r.Hostname != hostname {
+		return true
+	}
+	return processAlive(r.PID)
+}
+
+//LoadRecords Reads every box in the state file. A missing file has no boxes.
+func LoadRecords(path string) ([]Record, error) {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	err = json.Unmarshal(buf, &records)
+	return records, err
+}
+
+//SaveRecord Adds the record to the state file or updates the existing entry with the same ID
+func SaveRecord(path string, record Record) error {
+	unlock, err := lockState(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	records, err := LoadRecords(path)
+	if err != nil {
+		return err
+	}
+	record.UpdatedAt = time.Now()
+	for i := range records {
+		if records[i].ID == record.ID {
+			records[i] = record
+			return writeRecords(path, records)
+		}
+	}
+	return writeRecords(path, append(records, record))
+}
+
+//RemoveRecord Drops the box from the state file once it has been deleted
+func RemoveRecord(path string, id string) error {
+	unlock, err := lockState(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	records, err := LoadRecords(path)
+	if err != nil {
+		return err
+	}
+	kept := records[:0]
+	for _, r := range records {
+		if r.ID != id {
+			kept = append(kept, r)
+		}
+	}
+	return writeRecords(path, kept)
+}
+
+// lockState takes an exclusive lock file next to the state file so concurrent
+// ulacli processes can't lose each other's changes between load and write.
+// Locks left behind by a process that died are broken.
+func lockState(path string) (func(), error) {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = f.WriteString(strconv.Itoa(os.Getpid()))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, err
+			}
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if staleLock(lockPath) {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errStateLocked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func staleLock(lockPath string) bool {
+	buf, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		// Released while we looked, try again straight away
+		return os.IsNotExist(err)
+	}
+	pid, err := strconv.Atoi(string(buf))
+	if err == nil {
+		return !processAlive(pid)
+	}
+	// The owner may not have written its PID yet
+	info, err := os.Stat(lockPath)
+	return err == nil && time.Since(info.ModTime()) > staleLockAge
+}
+
+// Several ulacli processes may share the state file so write a temporary file
+// and rename it into place rather than truncating the real one
+func writeRecords(path string, records []Record) error {
+	if records == nil {
+		records = []Record{}
+	}
+	buf, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(buf)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
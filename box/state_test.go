@@ -0,0 +1,138 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package box
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulacli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "boxes.json")
+
+	records, err := LoadRecords(path)
+	if err != nil || len(records) != 0 {
+		t.Fatal("Missing state file should have no boxes")
+	}
+
+	first := NewRecord("1", "ubuntu")
+	second := NewRecord("2", "debian")
+	if err := SaveRecord(path, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveRecord(path, second); err != nil {
+		t.Fatal(err)
+	}
+	second.Image = "kali"
+	if err := SaveRecord(path, second); err != nil {
+		t.Fatal(err)
+	}
+	records, _ = LoadRecords(path)
+	if len(records) != 2 || records[1].Image != "kali" {
+		t.Fatalf("Expected 2 boxes with the second updated, got %+v", records)
+	}
+
+	if err := RemoveRecord(path, "1"); err != nil {
+		t.Fatal(err)
+	}
+	records, _ = LoadRecords(path)
+	if len(records) != 1 || records[0].ID != "2" {
+		t.Fatalf("Expected only box 2 to remain, got %+v", records)
+	}
+}
+
+func TestConcurrentSaveRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulacli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "boxes.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := SaveRecord(path, NewRecord(id, "ubuntu")); err != nil {
+				t.Error(err)
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+
+	records, err := LoadRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 20 {
+		t.Fatalf("Expected 20 boxes, got %d", len(records))
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatal("The lock file should be removed")
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulacli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "boxes.json")
+
+	// Left behind by a process that no longer exists
+	err = ioutil.WriteFile(path+".lock", []byte(fmt.Sprint(1<<22)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveRecord(path, NewRecord("1", "ubuntu")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordAttached(t *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(r *Record)
+		attached bool
+	}{
+		{"Current process", func(r *Record) {}, true},
+		{"Process gone", func(r *Record) { r.PID = 1 << 22 }, false},
+		{"Different host", func(r *Record) { r.PID = 1 << 22; r.Hostname = "elsewhere" }, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRecord("1", "ubuntu")
+			tc.modify(&r)
+			if r.Attached() != tc.attached {
+				t.Fatal("Failed")
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cypherpunkarmory/ulacli/credentials"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var store credentials.Store
+
+// tokenInConfig is true when the refresh token is kept in the config file. That is the
+// user's choice with "config", and what "auto" falls back to without a keyring, unless
+// an encrypted file store already exists there from an earlier version.
+func tokenInConfig() bool {
+	switch viper.GetString("credentialstore") {
+	case "config":
+		return true
+	case "", "auto":
+		if credentials.KeyringAvailable() {
+			return false
+		}
+		_, err := os.Stat(filepath.Join(configPath, credentials.FileName))
+		return err != nil
+	}
+	return false
+}
+
+// credentialStoreKind is the store to open, "auto" keeps using an existing file store
+func credentialStoreKind() string {
+	kind := viper.GetString("credentialstore")
+	if (kind == "" || kind == "auto") && !credentials.KeyringAvailable() {
+		return "file"
+	}
+	return kind
+}
+
+// credentialStore opens the store picked by the credentialstore config key once per run
+func credentialStore() (credentials.Store, error) {
+	if store != nil {
+		return store, nil
+	}
+	var err error
+	store, err = credentials.Open(credentialStoreKind(), configPath, readPassphrase)
+	return store, err
+}
+
+// readPassphrase asks twice when the store is created, a typo there would lock
+// the user out of the only copy of their API key
+func readPassphrase(create bool) (string, error) {
+	if passphrase := os.Getenv("ULACLI_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("set ULACLI_PASSPHRASE to unlock the credential store")
+	}
+	if !create {
+		return promptPassphrase("Passphrase for the ulacli credential store: ")
+	}
+	passphrase, err := promptPassphrase("Choose a passphrase for the ulacli credential store: ")
+	if err != nil {
+		return "", err
+	}
+	repeated, err := promptPassphrase("Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != repeated {
+		return "", errors.New("the passphrases don't match")
+	}
+	return passphrase, nil
+}
+
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(passphrase), err
+}
+
+// loadRefreshToken reads the refresh token for the current API endpoint.
+// ULACLI_TOKEN wins so CI jobs never need to log in or unlock a store.
+func loadRefreshToken() (string, error) {
+	if token := os.Getenv("ULACLI_TOKEN"); token != "" {
+		return token, nil
+	}
+	return loadStoredRefreshToken()
+}
+
+// loadStoredRefreshToken reads the refresh token saved on this machine. A plaintext
+// apikey in the config file is used as it is, after offering to move it into the
+// credential store when there is a terminal to ask on.
+func loadStoredRefreshToken() (string, error) {
+	plaintext := viper.GetString("apikey")
+	if tokenInConfig() {
+		return plaintext, nil
+	}
+	if plaintext != "" {
+		if stdinIsTerminal() {
+			migrateRefreshToken(plaintext)
+		}
+		return plaintext, nil
+	}
+	s, err := credentialStore()
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.Get(apiEndpoint)
+	if errors.Is(err, credentials.ErrNotFound) {
+		return "", nil
+	}
+	return token, err
+}
+
+// migrateRefreshToken moves a plaintext apikey into the credential store if the user
+// agrees. Declining keeps it in the config file for good.
+func migrateRefreshToken(plaintext string) {
+	s, err := credentialStore()
+	if err != nil {
+		reportError("Couldn't open the credential store: "+err.Error(), false)
+		return
+	}
+	var answer string
+	fmt.Fprintf(os.Stderr, "Your API key is stored unencrypted in %s.\nMove it into the %s? (Y/n): ", viper.ConfigFileUsed(), s.Name())
+	fmt.Scanln(&answer)
+	if strings.HasPrefix(strings.ToLower(answer), "n") {
+		viper.Set("credentialstore", "config")
+		err = writeConfig()
+		if err != nil {
+			reportError("Couldn't save your choice: "+err.Error(), false)
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Keeping it in the config file. Set credentialstore to auto to be asked again.")
+		return
+	}
+
+	err = storeRefreshToken(s, plaintext)
+	if err != nil {
+		reportError("Couldn't move your API key, it stays in the config file: "+err.Error(), false)
+		return
+	}
+	viper.Set("apikey", "")
+	err = writeConfig()
+	if err != nil {
+		reportError("Couldn't remove your API key from the config file: "+err.Error(), false)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Moved your API key from the config file into the %s\n", s.Name())
+}
+
+func saveRefreshToken(token string) error {
+	if tokenInConfig() {
+		viper.Set("apikey", token)
+		return writeConfig()
+	}
+	s, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	err = storeRefreshToken(s, token)
+	if err != nil {
+		return err
+	}
+	// An old plaintext key would otherwise win over the new one
+	if viper.GetString("apikey") != "" {
+		viper.Set("apikey", "")
+		return writeConfig()
+	}
+	return nil
+}
+
+// storeRefreshToken saves token and reads it back, so a store that can't be read
+// never ends up holding the only copy
+func storeRefreshToken(s credentials.Store, token string) error {
+	err := s.Set(apiEndpoint, token)
+	if err != nil {
+		return err
+	}
+	stored, err := s.Get(apiEndpoint)
+	if err != nil {
+		return err
+	}
+	if stored != token {
+		return errors.New("the credential store returned a different API key")
+	}
+	return nil
+}
+
+// deleteRefreshToken removes the refresh token from the store and from the config file
+func deleteRefreshToken() error {
+	if viper.GetString("apikey") != "" {
+		viper.Set("apikey", "")
+		err := writeConfig()
+		if err != nil {
+			return err
+		}
+	}
+	if tokenInConfig() {
+		return nil
+	}
+	s, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	return s.Delete(apiEndpoint)
+}
+
+// writeConfig saves the config file readable only by the user, it may hold secrets
+func writeConfig() error {
+	err := viper.WriteConfig()
+	if err != nil {
+		return err
+	}
+	return credentials.EnforcePrivate(viper.ConfigFileUsed())
+}
@@ -0,0 +1,90 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestTokenInConfig(t *testing.T) {
+	// No secret-tool, so there is never a keyring
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "")
+	dir, err := ioutil.TempDir("", "ulacli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { configPath = path }(configPath)
+	configPath = dir
+	defer viper.Set("credentialstore", "auto")
+
+	cases := []struct {
+		kind       string
+		fileExists bool
+		expected   bool
+		store      string
+	}{
+		{"auto", false, true, ""},
+		{"config", false, true, ""},
+		{"file", false, false, "file"},
+		// A file store from an earlier version keeps being used
+		{"auto", true, false, "file"},
+		{"config", true, true, ""},
+	}
+	for _, tc := range cases {
+		if tc.fileExists {
+			err = ioutil.WriteFile(filepath.Join(dir, "credentials.enc"), nil, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		viper.Set("credentialstore", tc.kind)
+		if tokenInConfig() != tc.expected {
+			t.Errorf("%s with file %v: expected the config file %v", tc.kind, tc.fileExists, tc.expected)
+		}
+		if tc.store != "" && credentialStoreKind() != tc.store {
+			t.Errorf("%s with file %v: expected the %s store, got %s", tc.kind, tc.fileExists, tc.store, credentialStoreKind())
+		}
+	}
+}
+
+func TestLoadStoredRefreshToken(t *testing.T) {
+	os.Setenv("ULACLI_TOKEN", "environment")
+	defer os.Unsetenv("ULACLI_TOKEN")
+	viper.Set("credentialstore", "config")
+	viper.Set("apikey", "saved")
+	defer viper.Set("credentialstore", "auto")
+	defer viper.Set("apikey", "")
+
+	// logout revokes and deletes the saved key, never the one from the environment
+	token, err := loadStoredRefreshToken()
+	if err != nil || token != "saved" {
+		t.Fatalf("Expected the saved API key, got %q %v", token, err)
+	}
+	token, err = loadRefreshToken()
+	if err != nil || token != "environment" {
+		t.Fatalf("Expected ULACLI_TOKEN to win, got %q %v", token, err)
+	}
+}
@@ -0,0 +1,68 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var extendCmd = &cobra.Command{
+	Use:   "extend <id> <duration>",
+	Short: "Give a box more time before it expires",
+	Long: "Give a box more time before it expires.\n" +
+		"Example: `ulacli extend 42 2h` keeps box 42 running for two more hours.\n" +
+		"Only boxes started with a TTL (`ulacli start --ttl 4h`) expire.",
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		duration, err := time.ParseDuration(args[1])
+		if err != nil || duration <= 0 {
+			reportError("Invalid duration "+args[1]+", use something like 30m or 2h", true)
+		}
+		requireSession()
+		extendBox(args[0], duration)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(extendCmd)
+}
+
+func extendBox(id string, duration time.Duration) {
+	b, err := restAPI.GetBoxAPI(id)
+	if err != nil {
+		reportAPIError("", err)
+	}
+	if b.ExpiresAt == nil {
+		reportError("Box "+id+" has no expiry to extend", true)
+	}
+
+	// An expired box that is still around gets the extra time from now
+	expiresAt := *b.ExpiresAt
+	if expiresAt.Before(time.Now()) {
+		expiresAt = time.Now()
+	}
+	b, err = restAPI.ExtendBoxAPI(id, expiresAt.Add(duration))
+	if err != nil {
+		reportAPIError("Extend failed: ", err)
+	}
+	fmt.Printf("Box %s now expires in %s ", b.ID, formatRemaining(b.ExpiresAt))
+	color.New(color.FgGreen, color.Bold).Printf("✔\n")
+}
@@ -0,0 +1,117 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/cypherpunkarmory/ulacli/backoff"
+	"github.com/cypherpunkarmory/ulacli/box"
+	"github.com/cypherpunkarmory/ulacli/restapi"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+const maxDeleteAttempts = 5
+
+var dryRun bool
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete boxes that were left running",
+	Long: "Delete boxes that were left running.\n" +
+		"ulacli remembers every box it starts until the box is deleted. If ulacli was killed,\n" +
+		"the machine crashed or deleting the box failed, the box is left running.\n" +
+		"`ulacli gc` deletes every remembered box whose ulacli process is no longer running.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !dryRun {
+			requireSession()
+		}
+		collectGarbage()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	gcCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only list the boxes that would be deleted")
+}
+
+func boxStatePath() string {
+	return filepath.Join(configPath, "boxes.json")
+}
+
+func collectGarbage() {
+	statePath := boxStatePath()
+	records, err := box.LoadRecords(statePath)
+	if err != nil {
+		reportError("Couldn't read "+statePath+": "+err.Error(), true)
+	}
+
+	failed := false
+	orphans := 0
+	for _, record := range records {
+		if record.Attached() {
+			continue
+		}
+		orphans++
+		if dryRun {
+			fmt.Printf("Would delete box %s (%s) started %s\n", record.ID, record.Image, record.CreatedAt.Format(time.RFC1123))
+			continue
+		}
+		err = deleteBoxWithRetry(record.ID)
+		if err != nil {
+			reportError("Couldn't delete box "+record.ID+": "+err.Error(), false)
+			failed = true
+			// Bump the timestamp so the state file shows when we last tried
+			_ = box.SaveRecord(statePath, record)
+			continue
+		}
+		err = box.RemoveRecord(statePath, record.ID)
+		if err != nil {
+			reportError("Couldn't update "+statePath+": "+err.Error(), false)
+		}
+		fmt.Printf("Deleted box %s ", record.ID)
+		color.New(color.FgGreen, color.Bold).Printf("✔\n")
+	}
+
+	if orphans == 0 {
+		fmt.Println("No abandoned boxes found")
+	}
+	if failed {
+		reportError("Some boxes could not be deleted, run `ulacli gc` again later", true)
+	}
+}
+
+func deleteBoxWithRetry(id string) error {
+	exponentialBackoff := backoff.NewExponentialBackOff()
+	var err error
+	for attempt := 1; attempt <= maxDeleteAttempts; attempt++ {
+		err = restAPI.DeleteBoxAPI(id)
+		if err == nil || errors.Is(err, restapi.ErrNotFound) {
+			// A box that is already gone doesn't need deleting
+			return nil
+		}
+		if attempt < maxDeleteAttempts {
+			time.Sleep(exponentialBackoff.NextBackOff())
+		}
+	}
+	return err
+}
@@ -118,5 +118,5 @@ func writeKeysToConfig(keyPath string, fileName string) error {
 	viper.Set("privatekeypath", keyPath+fileName+".pem")
 	viper.Set("publickeypath", keyPath+fileName+".pub")
 
-	return viper.WriteConfig()
+	return writeConfig()
 }
@@ -0,0 +1,57 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your running boxes",
+	Long:  "List your running boxes and how long each one has left before it expires.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireSession()
+		listBoxes()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
+
+func listBoxes() {
+	boxes, err := restAPI.ListBoxesAPI()
+	if err != nil {
+		reportAPIError("", err)
+	}
+	if len(boxes) == 0 {
+		fmt.Println("You have no running boxes")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tIMAGE\tIP ADDRESS\tEXPIRES IN")
+	for _, b := range boxes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.ID, b.Image, b.IPAddress, formatRemaining(b.ExpiresAt))
+	}
+	w.Flush()
+}
@@ -17,30 +17,65 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/cypherpunkarmory/ulacli/restapi"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var username string
 var password string
+var otp string
+var deviceLogin bool
+var passwordStdin bool
+
+const loginHint = "Use --password-stdin, ULACLI_USERNAME and ULACLI_PASSWORD, or ULACLI_TOKEN."
+
+type loginMethod int
+
+const (
+	loginPrompt loginMethod = iota
+	loginPassword
+	loginToken
+)
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login to UserLand Cloud.",
 	Long: "Login back into UserLand Cloud.\n" +
 		"Will prompt you for username and password, or you can provide them as optional arguments.\n" +
+		"On a shared or headless machine use --device to approve the login in a browser instead.\n" +
 		"If this is your first time using ulacli, you should use `ulacli setup` instead of `ulacli login`.",
 	Run: func(cmd *cobra.Command, args []string) {
-		if username != "" && password != "" {
-			login(username, password)
+		if deviceLogin {
+			loginWithDevice()
 			return
 		}
-		setupLogin() // This function is located in cmd/setup.go
+		if password != "" {
+			reportError("Warning: passing your password with -p leaves it in the process list and shell history, use --password-stdin instead", false)
+		}
+		if passwordStdin {
+			if password != "" {
+				reportError("Use either --password or --password-stdin", true)
+			}
+			password = readStdinLine("password")
+		}
+		method, err := chooseLogin(os.Getenv, stdinIsTerminal())
+		if err != nil {
+			reportError(err.Error(), true)
+		}
+		switch method {
+		case loginPassword:
+			login(username, password)
+		case loginToken:
+			loginWithToken(os.Getenv("ULACLI_TOKEN"))
+		default:
+			setupLogin() // This function is located in cmd/setup.go
+		}
 	},
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		initConfig()
@@ -51,13 +86,41 @@ func init() {
 	rootCmd.AddCommand(loginCmd)
 	loginCmd.Flags().StringVarP(&username, "username", "u", "", "Your UserLAnd Cloud username")
 	loginCmd.Flags().StringVarP(&password, "password", "p", "", "Your UserLAnd Cloud password")
+	loginCmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "Read the password from stdin")
+	loginCmd.Flags().BoolVar(&deviceLogin, "device", false, "Log in by approving this machine in a browser on another device")
+	loginCmd.Flags().StringVar(&otp, "otp", "", "Code from your authenticator app if two-factor authentication is on")
+}
+
+// chooseLogin picks how to log in. A username and password win, with flags and
+// --password-stdin before ULACLI_USERNAME and ULACLI_PASSWORD, then ULACLI_TOKEN,
+// then prompting, which needs a terminal. It fills in username and password from getenv.
+func chooseLogin(getenv func(string) string, interactive bool) (loginMethod, error) {
+	if username == "" {
+		username = getenv("ULACLI_USERNAME")
+	}
+	if password == "" {
+		password = getenv("ULACLI_PASSWORD")
+	}
+	if username != "" && password != "" {
+		return loginPassword, nil
+	}
+	if getenv("ULACLI_TOKEN") != "" {
+		return loginToken, nil
+	}
+	if !interactive {
+		return loginPrompt, errors.New("Can't prompt for input without a terminal. " + loginHint)
+	}
+	return loginPrompt, nil
 }
 
 func login(username string, password string) {
-	response, err := restAPI.Login(username, password)
+	response, err := restAPI.LoginMFA(username, password, otp)
+	if errors.Is(err, restapi.ErrMFARequired) && otp == "" {
+		response, err = restAPI.LoginMFA(username, password, readCode("Enter the code from your authenticator app: "))
+	}
 
 	if err != nil {
-		if err.Error() == "Must confirm email before you use the service" {
+		if errors.Is(err, restapi.ErrEmailUnconfirmed) {
 			resendEmail(username)
 			os.Exit(0)
 		} else {
@@ -65,18 +128,65 @@ func login(username string, password string) {
 		}
 	}
 
-	viper.Set("apikey", response.RefreshToken)
-	err = viper.WriteConfig()
+	saveLogin(response)
+}
+
+// loginWithToken checks the refresh token from ULACLI_TOKEN. It isn't saved, every
+// command reads the variable itself and the token shouldn't outlive the environment
+// it was handed to.
+func loginWithToken(token string) {
+	err := restAPI.StartSession(token)
+	if err != nil {
+		reportError("Login Failed: "+err.Error(), true)
+	}
+	refreshToken = token
+	printLoginSuccess()
+	fmt.Println("ULACLI_TOKEN is used for as long as it is set, it wasn't saved.")
+}
+
+// loginWithDevice logs in without typing a password here, the user approves
+// the login in a browser anywhere instead
+func loginWithDevice() {
+	authorization, err := restAPI.StartDeviceLogin()
+	if err != nil {
+		reportError("Login Failed: "+err.Error(), true)
+	}
+	fmt.Print("Open ")
+	color.New(color.FgGreen).Print(authorization.VerificationURI)
+	fmt.Print(" in a browser and enter the code ")
+	color.New(color.FgGreen, color.Bold).Println(authorization.UserCode)
+	if authorization.VerificationURIComplete != "" {
+		fmt.Println("Or go straight to " + authorization.VerificationURIComplete)
+	}
+	fmt.Println("Waiting for you to approve the login...")
 
+	response, err := restAPI.WaitForDeviceLogin(authorization)
 	if err != nil {
-		reportError("Couldn't write refresh token to config - are you able to write to ~/.config/userland/ulacli.toml?", true)
+		reportError("Login Failed: "+err.Error(), true)
 	}
+	saveLogin(response)
+}
+
+func saveLogin(response restapi.SessionResponse) {
+	refreshToken = response.RefreshToken
+	err := saveRefreshToken(response.RefreshToken)
+
+	if err != nil {
+		reportError("Couldn't save your API key: "+err.Error(), true)
+	}
+	printLoginSuccess()
+}
+
+func printLoginSuccess() {
 	fmt.Print("Login Succesful ")
 	d := color.New(color.FgGreen, color.Bold)
 	d.Printf("✔\n")
 }
 
 func resendEmail(username string) {
+	if !stdinIsTerminal() {
+		reportError("You need to confirm your email before you use this service. Follow the link in the confirmation email and try again.", true)
+	}
 	var resendKey string
 	fmt.Print("You need to confirm your email before you use this service.\nWould you like to resend your confirmation email? (Y/n): ")
 	fmt.Scanln(&resendKey)
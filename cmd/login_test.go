@@ -0,0 +1,64 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package cmd
+
+import (
+	"testing"
+)
+
+func TestChooseLogin(t *testing.T) {
+	cases := []struct {
+		name             string
+		username         string
+		password         string
+		env              map[string]string
+		interactive      bool
+		expected         loginMethod
+		expectedUsername string
+		expectedPassword string
+		fails            bool
+	}{
+		{"Flags", "flag", "stdin", map[string]string{"ULACLI_USERNAME": "env", "ULACLI_PASSWORD": "env", "ULACLI_TOKEN": "token"}, false, loginPassword, "flag", "stdin", false},
+		{"StdinBeforeEnvironment", "", "stdin", map[string]string{"ULACLI_USERNAME": "env", "ULACLI_PASSWORD": "env"}, false, loginPassword, "env", "stdin", false},
+		{"EnvironmentBeforeToken", "", "", map[string]string{"ULACLI_USERNAME": "env", "ULACLI_PASSWORD": "env", "ULACLI_TOKEN": "token"}, false, loginPassword, "env", "env", false},
+		{"Token", "", "", map[string]string{"ULACLI_TOKEN": "token"}, false, loginToken, "", "", false},
+		{"UsernameWithoutPasswordUsesToken", "flag", "", map[string]string{"ULACLI_TOKEN": "token"}, false, loginToken, "flag", "", false},
+		{"Prompt", "", "", nil, true, loginPrompt, "", "", false},
+		{"NoTerminal", "", "", nil, false, loginPrompt, "", "", true},
+		{"NoTerminalWithoutPassword", "flag", "", nil, false, loginPrompt, "flag", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			username, password = tc.username, tc.password
+			defer func() { username, password = "", "" }()
+			getenv := func(key string) string { return tc.env[key] }
+
+			method, err := chooseLogin(getenv, tc.interactive)
+			if (err != nil) != tc.fails {
+				t.Fatalf("Expected failure %v, got %v", tc.fails, err)
+			}
+			if method != tc.expected {
+				t.Fatalf("Expected method %d, got %d", tc.expected, method)
+			}
+			if username != tc.expectedUsername || password != tc.expectedPassword {
+				t.Fatalf("Expected %q/%q, got %q/%q", tc.expectedUsername, tc.expectedPassword, username, password)
+			}
+		})
+	}
+}
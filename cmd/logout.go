@@ -0,0 +1,77 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cypherpunkarmory/ulacli/restapi"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var logoutAll bool
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Log out of UserLAnd Cloud",
+	Long: "Log out of UserLAnd Cloud.\n" +
+		"Revokes your API key on the server and removes it from this machine.\n" +
+		"Use --all to end every session on your account, for example after losing a laptop.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logout()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+	logoutCmd.Flags().BoolVar(&logoutAll, "all", false, "Revoke every session on your account")
+}
+
+// logout revokes and removes the API key saved on this machine. ULACLI_TOKEN is
+// left alone, it isn't ours to delete and revoking it would leave the saved key behind.
+func logout() {
+	if os.Getenv("ULACLI_TOKEN") != "" {
+		reportError("ULACLI_TOKEN is set, it stays valid. Logging out the API key saved on this machine.", false)
+	}
+	token, err := loadStoredRefreshToken()
+	if err != nil {
+		reportError("Couldn't read your API key: "+err.Error(), true)
+	}
+	if token == "" {
+		fmt.Println("You are not logged in")
+		return
+	}
+
+	restAPI.SetRefreshToken(token)
+	err = restAPI.Logout(logoutAll)
+	// An unauthorized key was revoked already, there is nothing left to do on the server
+	if err != nil && !errors.Is(err, restapi.ErrUnauthorized) {
+		reportError("Couldn't revoke your API key on the server: "+err.Error(), false)
+		reportError("It is removed from this machine but stays valid until it expires", false)
+	}
+
+	err = deleteRefreshToken()
+	if err != nil {
+		reportError("Couldn't remove your API key: "+err.Error(), true)
+	}
+	fmt.Print("Logged out ")
+	color.New(color.FgGreen, color.Bold).Printf("✔\n")
+}
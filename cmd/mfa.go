@@ -0,0 +1,144 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"rsc.io/qr"
+)
+
+// Light modules around the code so scanners can find its edges
+const qrQuietZone = 2
+
+var mfaCmd = &cobra.Command{
+	Use:   "mfa",
+	Short: "Manage two-factor authentication",
+	Long: "Manage two-factor authentication.\n" +
+		"With two-factor authentication on, logging in also needs a code from an authenticator app.",
+}
+
+var mfaEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Turn on two-factor authentication",
+	Long: "Turn on two-factor authentication.\n" +
+		"Shows a QR code and secret to add to your authenticator app, then asks for a code to check it works.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireSession()
+		enableMFA()
+	},
+}
+
+var mfaDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Turn off two-factor authentication",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireSession()
+		disableMFA()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mfaCmd)
+	mfaCmd.AddCommand(mfaEnableCmd)
+	mfaCmd.AddCommand(mfaDisableCmd)
+}
+
+func enableMFA() {
+	setup, err := restAPI.EnableMFA()
+	if err != nil {
+		reportError("Couldn't enable two-factor authentication: "+err.Error(), true)
+	}
+
+	fmt.Println("Scan this QR code with your authenticator app:")
+	fmt.Println()
+	err = printQR(os.Stdout, setup.URI)
+	if err != nil {
+		fmt.Println("(The QR code couldn't be drawn, use the secret below)")
+	}
+	fmt.Println()
+	fmt.Print("Or enter this secret by hand: ")
+	color.New(color.FgGreen).Println(setup.Secret)
+	fmt.Println()
+
+	err = restAPI.ConfirmMFA(readCode("Enter the code from your app to finish: "))
+	if err != nil {
+		reportError("Two-factor authentication was not enabled: "+err.Error(), true)
+	}
+	fmt.Print("Two-factor authentication enabled ")
+	color.New(color.FgGreen, color.Bold).Printf("✔\n")
+}
+
+func disableMFA() {
+	err := restAPI.DisableMFA(readCode("Enter the code from your authenticator app: "))
+	if err != nil {
+		reportError("Couldn't disable two-factor authentication: "+err.Error(), true)
+	}
+	fmt.Print("Two-factor authentication disabled ")
+	color.New(color.FgGreen, color.Bold).Printf("✔\n")
+}
+
+// readCode reads a one time code, authenticator apps often show it with a space in the middle
+func readCode(prompt string) string {
+	code, err := readLine(prompt, "Pass the code with --otp.")
+	if err != nil {
+		reportError("Error reading the code", true)
+	}
+	return strings.Replace(code, " ", "", -1)
+}
+
+// printQR draws text as a QR code with half block characters, two modules per
+// character line. Light modules are drawn so it reads on dark terminals.
+func printQR(w io.Writer, text string) error {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return err
+	}
+	light := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= code.Size || y >= code.Size {
+			return true
+		}
+		return !code.Black(x, y)
+	}
+
+	var b strings.Builder
+	for y := -qrQuietZone; y < code.Size+qrQuietZone; y += 2 {
+		for x := -qrQuietZone; x < code.Size+qrQuietZone; x++ {
+			top, bottom := light(x, y), light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,52 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"rsc.io/qr"
+)
+
+func TestPrintQR(t *testing.T) {
+	uri := "otpauth://totp/UserLAnd:me@example.com?secret=JBSWY3DPEHPK3PXP&issuer=UserLAnd"
+	var out bytes.Buffer
+	err := printQR(&out, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := qr.Encode(uri, qr.M)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	size := code.Size + 2*qrQuietZone
+	if len(lines) != (size+1)/2 {
+		t.Fatalf("Expected %d lines, got %d", (size+1)/2, len(lines))
+	}
+	for _, line := range lines {
+		if len([]rune(line)) != size {
+			t.Fatalf("Expected lines %d wide, got %d", size, len([]rune(line)))
+		}
+	}
+	// The first two rows of the top left finder pattern: a dark row above a ring
+	if !strings.HasPrefix(string([]rune(lines[1])[qrQuietZone:]), " ▄▄▄▄▄ ") {
+		t.Fatalf("Unexpected finder pattern corner %q", lines[1])
+	}
+}
@@ -0,0 +1,103 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var resetCodeStdin bool
+
+var passwordCmd = &cobra.Command{
+	Use:   "password",
+	Short: "Reset or change your password",
+	Long:  "Reset a forgotten password or change the password of the account you are logged in to.",
+}
+
+var passwordResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Reset a forgotten password",
+	Long: "Reset a forgotten password.\n" +
+		"Sends a reset code to your email, then asks for the code and your new password.\n" +
+		"If you already have a code use --code-stdin to pass the code and the new password\n" +
+		"on the first two lines of stdin.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		resetPassword()
+	},
+}
+
+var passwordChangeCmd = &cobra.Command{
+	Use:   "change",
+	Short: "Change your password",
+	Long:  "Change the password of the account you are logged in to.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireSession()
+		changePassword()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(passwordCmd)
+	passwordCmd.AddCommand(passwordResetCmd)
+	passwordCmd.AddCommand(passwordChangeCmd)
+	passwordResetCmd.Flags().BoolVar(&resetCodeStdin, "code-stdin", false, "Read the reset code and then the new password from stdin")
+}
+
+func resetPassword() {
+	var code, password string
+	if resetCodeStdin {
+		code = readStdinLine("reset code")
+		password = readStdinLine("new password")
+	} else {
+		email, err := readLine("Enter Email: ", "Use --code-stdin.")
+		if err != nil || email == "" {
+			reportError("Error reading email", true)
+		}
+		err = restAPI.RequestPasswordReset(email)
+		if err != nil {
+			reportError("Couldn't request a password reset: "+err.Error(), true)
+		}
+		fmt.Printf("We sent a reset code to %s.\n", email)
+		code = readHidden("Enter the reset code: ", "Use --code-stdin.")
+		if code == "" {
+			reportError("Error reading the reset code", true)
+		}
+		password = readNewPassword()
+	}
+
+	err := restAPI.ResetPassword(code, password)
+	if err != nil {
+		reportError("Password reset failed: "+err.Error(), true)
+	}
+	fmt.Print("Password reset, log in with `ulacli login` ")
+	color.New(color.FgGreen, color.Bold).Printf("✔\n")
+}
+
+func changePassword() {
+	current := readPassword("Enter Current Password: ")
+	err := restAPI.ChangePassword(current, readNewPassword())
+	if err != nil {
+		reportError("Password change failed: "+err.Error(), true)
+	}
+	fmt.Print("Password changed ")
+	color.New(color.FgGreen, color.Bold).Printf("✔\n")
+}
@@ -19,10 +19,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"runtime"
 
+	"github.com/cypherpunkarmory/ulacli/credentials"
+	"github.com/cypherpunkarmory/ulacli/proxy"
 	"github.com/cypherpunkarmory/ulacli/restapi"
 	rollbar "github.com/rollbar/rollbar-go"
 
@@ -43,6 +46,9 @@ var refreshToken string
 var restAPI restapi.RestClient
 var rollbarToken string
 var sshEndpoint string
+var proxyFunc proxy.Func
+var traceHTTP bool
+var traceHTTPFile string
 var image string
 var logLevel string
 
@@ -59,10 +65,6 @@ var rootCmd = &cobra.Command{
 		"Look at the commands below to see what else you can do.",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		initConfig()
-		err := tryStartSession()
-		if err != nil {
-			os.Exit(1)
-		}
 		rollbar.SetToken(rollbarToken)
 		rollbar.SetEnvironment("production")
 		rollbar.SetCodeVersion(version)
@@ -86,6 +88,8 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Default is $XDG_HOME/userland/~.ulacli.toml")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "loglevel", "", "Set the loglevel")
 	rootCmd.PersistentFlags().BoolVar(&crashReporting, "crashreporting", false, "Send crash reports to the developers")
+	rootCmd.PersistentFlags().BoolVar(&traceHTTP, "trace-http", false, "Log API requests and responses with credentials redacted to stderr")
+	rootCmd.PersistentFlags().StringVar(&traceHTTPFile, "trace-http-file", "", "Log API requests and responses with credentials redacted to this file instead")
 	err := rootCmd.PersistentFlags().MarkHidden("loglevel")
 	if err != nil {
 		panic(err)
@@ -100,6 +104,21 @@ func init() {
 	viper.SetDefault("publickeypath", "")
 	viper.SetDefault("privatekeypath", "")
 	viper.SetDefault("loglevel", "ERROR")
+	viper.SetDefault("idletimeout", "0s")
+	viper.SetDefault("ttl", "0s")
+	viper.SetDefault("provision", []string{})
+	// Not "env" and "passenv", AutomaticEnv would read the shell's $ENV into them
+	viper.SetDefault("sessionpassenv", []string{"LANG", "TZ", "EDITOR"})
+	viper.SetDefault("sessionenv", []string{})
+	viper.SetDefault("requesttimeout", "30s")
+	viper.SetDefault("apitimeout", "2m")
+	viper.SetDefault("retries", 3)
+	viper.SetDefault("credentialstore", "auto")
+	viper.SetDefault("proxy", "")
+	viper.SetDefault("cabundle", "")
+	viper.SetDefault("clientcert", "")
+	viper.SetDefault("clientkey", "")
+	viper.SetDefault("pinnedcert", "")
 
 	rootCmd.SetHelpCommand(&cobra.Command{
 		Use:    "no-help",
@@ -127,7 +146,23 @@ func initConfig() {
 	viper.AutomaticEnv() // read in environment variables that match
 }
 
+// requireSession is called by commands that use the API, others never need to authenticate.
+// With a cached access token it doesn't reach the API, so the commands report a revoked
+// API key or an outdated client through reportAPIError instead.
+func requireSession() {
+	err := tryStartSession()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
 func tryStartSession() error {
+	var err error
+	refreshToken, err = loadRefreshToken()
+	if err != nil {
+		reportError("Couldn't read your API key: "+err.Error(), false)
+		return err
+	}
 	if refreshToken == "" {
 		reportError("You need to login using `ulacli login` first.", false)
 		return errors.New("no refresh token")
@@ -135,12 +170,11 @@ func tryStartSession() error {
 
 	// StartSession will set the internal state of the RestClient
 	// to the correct API key
-	err := restAPI.StartSession(refreshToken)
+	err = restAPI.StartSession(refreshToken)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "incompatiable with the api") {
-			reportError("Your ulacli client is out of date. Please use `ulacli update` to get the latest version.", false)
-			confirmAndSelfUpdate()
+		if errors.Is(err, restapi.ErrVersionMismatch) {
+			reportOutdated()
 			return errors.New("error starting session")
 		}
 		reportError("Error starting session", false)
@@ -150,6 +184,24 @@ func tryStartSession() error {
 	return nil
 }
 
+// reportAPIError exits with err from a call that needed the session, telling the
+// user how to fix an outdated client or an API key that stopped working
+func reportAPIError(prefix string, err error) {
+	switch {
+	case errors.Is(err, restapi.ErrVersionMismatch):
+		reportOutdated()
+		os.Exit(1)
+	case errors.Is(err, restapi.ErrUnauthorized):
+		reportError("Your API key is no longer valid. You need to login using `ulacli login` again.", true)
+	}
+	reportError(prefix+err.Error(), true)
+}
+
+func reportOutdated() {
+	reportError("Your ulacli client is out of date. Please use `ulacli update` to get the latest version.", false)
+	confirmAndSelfUpdate()
+}
+
 func tryReadConfig() (err error) {
 	if configFile != "" {
 		// Use config file from the flag.
@@ -172,6 +224,8 @@ func tryReadConfig() (err error) {
 
 		publicKeyPath = fixFilePath(publicKeyPath)
 		privateKeyPath = fixFilePath(privateKeyPath)
+		// Older versions created the config world readable with the API key inside
+		_ = credentials.EnforcePrivate(viper.ConfigFileUsed())
 	} else {
 		if _, err := os.Stat(configPath + string(os.PathSeparator) + ".ulacli.toml"); err != nil {
 			if os.IsNotExist(err) {
@@ -185,6 +239,7 @@ func tryReadConfig() (err error) {
 					reportError("Couldn't generate default config file", false)
 					return err
 				}
+				_ = credentials.EnforcePrivate(configPath + string(os.PathSeparator) + ".ulacli.toml")
 			}
 		} else {
 			reportError("You have an issue in your current config", false)
@@ -194,6 +249,51 @@ func tryReadConfig() (err error) {
 		fmt.Println("Generated default config.")
 		_ = tryReadConfig()
 	}
-	restAPI = restapi.NewRestClient(apiEndpoint, refreshToken)
+	// An empty proxy falls back to HTTPS_PROXY and NO_PROXY
+	proxyFunc = proxy.FromConfig(viper.GetString("proxy"))
+	tlsConfig, err := restapi.TLSConfig(restapi.TLSOptions{
+		CABundle:   fixFilePath(viper.GetString("cabundle")),
+		ClientCert: fixFilePath(viper.GetString("clientcert")),
+		ClientKey:  fixFilePath(viper.GetString("clientkey")),
+		PinnedCert: viper.GetString("pinnedcert"),
+	})
+	if err != nil {
+		reportError("Couldn't set up TLS: "+err.Error(), false)
+		return err
+	}
+	var middlewares []restapi.Middleware
+	if traceHTTP || traceHTTPFile != "" {
+		trace, err := openTrace(traceHTTPFile)
+		if err != nil {
+			reportError("Couldn't open the HTTP trace: "+err.Error(), false)
+			return err
+		}
+		middlewares = append(middlewares, restapi.Trace(trace))
+	}
+	restAPI = restapi.NewRestClient(apiEndpoint, refreshToken,
+		restapi.WithTokenCache(restapi.NewFileTokenCache(filepath.Join(configPath, "session.json"))),
+		restapi.WithProxy(proxyFunc),
+		restapi.WithTLSConfig(tlsConfig),
+		restapi.WithRetries(viper.GetInt("retries")),
+		restapi.WithMiddleware(middlewares...),
+		restapi.WithTimeouts(viper.GetDuration("requesttimeout"), viper.GetDuration("apitimeout")),
+		restapi.WithUserAgent(userAgent()),
+	)
 	return nil
 }
+
+// openTrace appends to path so several runs can go in one transcript, without a path it's stderr
+func openTrace(path string) (io.Writer, error) {
+	if path == "" {
+		return os.Stderr, nil
+	}
+	return os.OpenFile(fixFilePath(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+}
+
+func userAgent() string {
+	v := version
+	if v == "" {
+		v = "dev"
+	}
+	return fmt.Sprintf("ulacli/%s (%s/%s)", v, runtime.GOOS, runtime.GOARCH)
+}
@@ -21,11 +21,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"syscall"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 var setupCmd = &cobra.Command{
@@ -34,37 +32,8 @@ var setupCmd = &cobra.Command{
 	Long: "Setup Userland Cloud.\n" +
 		"This will ask you for your Userland credentials and help you create pub/priv keys if needed.",
 	Run: func(cmd *cobra.Command, args []string) {
-		var setupKey string
 		setupLogin()
-		fmt.Print("\nAn SSH key-pair is required for connecting securely to Userland Cloud, do you want us to create one? \n(Y/N): ")
-		fmt.Scanln(&setupKey)
-		setupKey = strings.ToLower(setupKey)
-		if setupKey != "" && !strings.HasPrefix(setupKey, "y") && !strings.HasPrefix(setupKey, "n") {
-			reportError("Invalid input", true)
-		}
-		if strings.HasPrefix(setupKey, "n") {
-			fmt.Print("\nAn existing SSH key-pair can also be used by setting it's path in the config file located at:\n\t")
-			configFilePath := configPath + string(os.PathSeparator) + ".ulacli.toml"
-			green := color.New(color.FgGreen)
-			green.Printf(configFilePath)
-			fmt.Print("\n\nOtherwise, a new SSH key pair can be generated by running the command: ")
-			fmt.Print(color.GreenString("`ulacli generate-key`"), "\n\n")
-			return
-		}
-		path := configPath + string(filepath.Separator)
-		err := generateKey(path, "userland_key")
-		if err != nil {
-			reportError("Could not generate key", true)
-		}
-		fmt.Print("Generated keys in the current directory ")
-		d := color.New(color.FgGreen, color.Bold)
-		d.Printf("✔\n")
-		err = writeKeysToConfig(path, "userland_key")
-		if err != nil {
-			reportError("Failed to update config file", true)
-		}
-		fmt.Print("Config file updated")
-		d.Printf(" ✔\n")
+		setupKeys()
 	},
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		initConfig()
@@ -75,20 +44,61 @@ func init() {
 	rootCmd.AddCommand(setupCmd)
 }
 
-func setupLogin() {
-	var username string
-	var password string
-	fmt.Print("Enter Username: ")
-	_, err := fmt.Scanln(&username)
+// setupKeys offers to create the SSH key-pair used to connect to boxes
+func setupKeys() {
+	setupKey, _ := readLine("\nAn SSH key-pair is required for connecting securely to Userland Cloud, do you want us to create one? \n(Y/N): ",
+		"Create a key with `ulacli generate-key` instead.")
+	setupKey = strings.ToLower(setupKey)
+	if setupKey != "" && !strings.HasPrefix(setupKey, "y") && !strings.HasPrefix(setupKey, "n") {
+		reportError("Invalid input", true)
+	}
+	if strings.HasPrefix(setupKey, "n") {
+		fmt.Print("\nAn existing SSH key-pair can also be used by setting it's path in the config file located at:\n\t")
+		configFilePath := configPath + string(os.PathSeparator) + ".ulacli.toml"
+		green := color.New(color.FgGreen)
+		green.Print(configFilePath)
+		fmt.Print("\n\nOtherwise, a new SSH key pair can be generated by running the command: ")
+		fmt.Print(color.GreenString("`ulacli generate-key`"), "\n\n")
+		return
+	}
+	path := configPath + string(filepath.Separator)
+	err := generateKey(path, "userland_key")
 	if err != nil {
-		reportError("Error reading username", true)
+		reportError("Could not generate key", true)
 	}
-	fmt.Print("Enter Password: ")
-	bytePassword, err := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Print("Generated keys in the current directory ")
+	d := color.New(color.FgGreen, color.Bold)
+	d.Printf("✔\n")
+	err = writeKeysToConfig(path, "userland_key")
+	if err != nil {
+		reportError("Failed to update config file", true)
+	}
+	fmt.Print("Config file updated")
+	d.Printf(" ✔\n")
+}
+
+func setupLogin() {
+	username, err := readLine("Enter Username: ", loginHint)
 	if err != nil {
-		reportError("Error reading password", true)
+		reportError("Error reading username", true)
 	}
-	fmt.Println()
-	password = string(bytePassword)
+	password := readPassword("Enter Password: ")
 	login(username, password)
 }
+
+// readNewPassword asks for a new password twice so a typo doesn't lock the user out
+func readNewPassword() string {
+	password := readPassword("Choose a Password: ")
+	if password == "" {
+		reportError("The password can't be empty", true)
+	}
+	if readPassword("Confirm Password: ") != password {
+		reportError("The passwords don't match", true)
+	}
+	return password
+}
+
+// readPassword prompts for a password without echoing it
+func readPassword(prompt string) string {
+	return readHidden(prompt, loginHint)
+}
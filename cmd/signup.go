@@ -0,0 +1,90 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cypherpunkarmory/ulacli/backoff"
+	"github.com/cypherpunkarmory/ulacli/restapi"
+	"github.com/spf13/cobra"
+)
+
+// How long signup waits for the confirmation link to be clicked
+const confirmationTimeout = 30 * time.Minute
+
+var signupCmd = &cobra.Command{
+	Use:   "signup",
+	Short: "Create a UserLAnd Cloud account",
+	Long: "Create a UserLAnd Cloud account.\n" +
+		"Asks for your email and a password, then waits while you confirm your email address\n" +
+		"and finishes by setting up your SSH keys just like `ulacli setup`.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		signup()
+	},
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(signupCmd)
+}
+
+func signup() {
+	email, err := readLine("Enter Email: ", "")
+	if err != nil || email == "" {
+		reportError("Error reading email", true)
+	}
+	password := readNewPassword()
+
+	err = restAPI.Signup(email, password)
+	if err != nil {
+		reportError("Signup Failed: "+err.Error(), true)
+	}
+	fmt.Printf("We sent an email to %s. Follow the link in it to confirm your account.\n", email)
+	fmt.Println("Waiting for confirmation, press Ctrl-C to stop and run `ulacli setup` later...")
+
+	saveLogin(waitForConfirmation(email, password))
+	setupKeys()
+}
+
+// waitForConfirmation keeps logging in until the account's email address is confirmed
+func waitForConfirmation(email string, password string) restapi.SessionResponse {
+	exponentialBackoff := backoff.NewExponentialBackOff()
+	exponentialBackoff.InitialInterval = 2 * time.Second
+	exponentialBackoff.MaxInterval = 30 * time.Second
+	exponentialBackoff.MaxElapsedTime = confirmationTimeout
+	exponentialBackoff.Reset()
+	for {
+		response, err := restAPI.Login(email, password)
+		if err == nil {
+			return response
+		}
+		if !errors.Is(err, restapi.ErrEmailUnconfirmed) {
+			reportError("Login Failed: "+err.Error(), true)
+		}
+		wait := exponentialBackoff.NextBackOff()
+		if wait == backoff.Stop {
+			reportError("Your email is still not confirmed, run `ulacli setup` once it is", true)
+		}
+		time.Sleep(wait)
+	}
+}
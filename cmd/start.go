@@ -17,11 +17,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/url"
 	"os"
-	"github.com/spf13/cobra"
-	"github.com/cypherpunkarmory/ulacli/box"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/cypherpunkarmory/ulacli/box"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // startCmd represents the http command
@@ -41,12 +48,52 @@ var startCmd = &cobra.Command{
 		if len(args) == 1 {
 			image = args[0]
 		}
+		err := checkDurations(viper.GetDuration("ttl"), viper.GetDuration("idletimeout"))
+		if err != nil {
+			reportError(err.Error(), true)
+		}
+		requireSession()
 		startBox()
 	},
 }
 
+var quiet bool
+var timings bool
+var provisionScripts []string
+var setEnvFlags []string
+var passEnvFlags []string
+
 func init() {
 	rootCmd.AddCommand(startCmd)
+	startCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Don't report progress while the box starts")
+	startCmd.Flags().BoolVar(&timings, "timings", false, "Print how long each startup phase took")
+	startCmd.Flags().Duration("idle-timeout", 0, "Shut the box down after this long without any input or output, e.g. 30m (0 disables)")
+	viper.BindPFlag("idletimeout", startCmd.Flags().Lookup("idle-timeout"))
+	startCmd.Flags().Duration("ttl", 0, "Delete the box automatically after this long, e.g. 4h (0 keeps it until you disconnect)")
+	viper.BindPFlag("ttl", startCmd.Flags().Lookup("ttl"))
+	startCmd.Flags().StringArrayVar(&provisionScripts, "provision", nil, "Script to run on the box before the shell opens, can be repeated")
+	startCmd.Flags().StringArrayVar(&setEnvFlags, "env", nil, "Set KEY=VAL in the box session, can be repeated")
+	startCmd.Flags().StringArrayVar(&passEnvFlags, "pass-env", nil, "Pass the local value of KEY into the box session, can be repeated")
+}
+
+// checkDurations rejects a ttl or idle timeout that is negative or under a second, 0 turns either off.
+// A ttl that short reaches the API as zero or less, and the idle monitor can't tick that fast.
+func checkDurations(ttl time.Duration, idleTimeout time.Duration) error {
+	if ttl != 0 && ttl < time.Second {
+		return errors.New("invalid ttl " + ttl.String() + ", use something like 30m or 4h, or 0 to keep the box")
+	}
+	if idleTimeout != 0 && idleTimeout < time.Second {
+		return errors.New("invalid idle timeout " + idleTimeout.String() + ", use something like 30m, or 0 to turn it off")
+	}
+	return nil
+}
+
+// startEnv builds the box session environment from the config and the --pass-env and --env flags
+func startEnv() ([]string, error) {
+	return sessionEnv(
+		append(viper.GetStringSlice("sessionpassenv"), passEnvFlags...),
+		append(viper.GetStringSlice("sessionenv"), setEnvFlags...),
+	)
 }
 
 func startBox() {
@@ -55,12 +102,54 @@ func startBox() {
 		os.Exit(3)
 	}
 
-	response, err := restAPI.CreateBoxAPI(publicKey, image)
+	// Check the scripts up front so a typo doesn't cost a box
+	scripts := append(viper.GetStringSlice("provision"), provisionScripts...)
+	for i, script := range scripts {
+		scripts[i] = fixFilePath(script)
+		if _, err := os.Stat(scripts[i]); err != nil {
+			reportError("Can't read provisioning script "+script, true)
+		}
+	}
 
+	env, err := startEnv()
 	if err != nil {
 		reportError(err.Error(), true)
 	}
 
+	ctx, stopInterrupt := interruptible()
+	warnQuota(ctx)
+	progress := box.NewProgress(quiet, timings)
+	progress.Start("Creating box")
+	// Cancelling during the quota check never sends the create request
+	sent := ctx.Err() == nil
+	response, err := restAPI.CreateBoxAPIContext(ctx, publicKey, image, viper.GetDuration("ttl"))
+	stopInterrupt()
+
+	if err == context.Canceled {
+		progress.Fail()
+		if sent {
+			// The server may have created the box before we stopped waiting, and
+			// without its ID it can't be recorded for `ulacli gc`
+			reportError("Cancelled starting the box. It may have been created anyway, check `ulacli list`.", true)
+		}
+		reportError("Cancelled starting the box", true)
+	}
+	if err != nil {
+		progress.Fail()
+		reportAPIError("", err)
+	}
+	progress.Done()
+	if response.ExpiresAt != nil && !quiet {
+		fmt.Printf("This box expires in %s\n", formatRemaining(response.ExpiresAt))
+	}
+
+	// Remember the box so `ulacli gc` can find it if we never get to delete it
+	statePath := boxStatePath()
+	err = box.SaveRecord(statePath, box.NewRecord(response.ID, image))
+	if err != nil {
+		reportError("Couldn't record your box in "+statePath+": "+err.Error(), false)
+	}
+
 	connectionURL, err := url.Parse(sshEndpoint)
 	if err != nil {
 		reportError("The ssh endpoint is not a valid URL", true)
@@ -74,7 +163,33 @@ func startBox() {
 		PrivateKeyPath:     privateKeyPath,
 		LocalPort:          port,
 		LogLevel:           logLevel,
+		Progress:           progress,
+		StatePath:          statePath,
+		IdleTimeout:        viper.GetDuration("idletimeout"),
+		Provision:          scripts,
+		Env:                env,
+		Proxy:              proxyFunc,
 	}
 	semaphore := box.Semaphore{}
 	box.StartBox(&boxConfig, nil, &semaphore)
 }
+
+// interruptible returns a context that Ctrl-C cancels, so the calls before the box
+// exists don't have to wait on a hung API. stop hands the signals back before
+// box.StartBox installs its own handler.
+func interruptible() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(interrupt)
+		cancel()
+	}
+}
@@ -0,0 +1,71 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestStartEnv(t *testing.T) {
+	// POSIX sh and ksh read $ENV, it must not end up in the box session
+	os.Setenv("ENV", "/home/u/.shrc")
+	os.Setenv("PASSENV", "/home/u/.shrc")
+	os.Setenv("LANG", "en_US.UTF-8")
+	defer os.Unsetenv("ENV")
+	defer os.Unsetenv("PASSENV")
+	viper.AutomaticEnv()
+	setEnvFlags = []string{"FOO=bar"}
+	defer func() { setEnvFlags = nil }()
+
+	env, err := startEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"LANG=en_US.UTF-8", "FOO=bar"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Fatalf("Expected %v, got %v", expected, env)
+	}
+}
+
+func TestCheckDurations(t *testing.T) {
+	cases := []struct {
+		ttl         time.Duration
+		idleTimeout time.Duration
+		fails       bool
+	}{
+		{0, 0, false},
+		{4 * time.Hour, 30 * time.Minute, false},
+		{time.Second, time.Second, false},
+		{500 * time.Millisecond, 0, true},
+		{-time.Hour, 0, true},
+		{0, time.Nanosecond, true},
+		{0, -time.Minute, true},
+	}
+	for _, tc := range cases {
+		err := checkDurations(tc.ttl, tc.idleTimeout)
+		if (err != nil) != tc.fails {
+			t.Errorf("ttl %s, idle timeout %s: expected failure %v, got %v", tc.ttl, tc.idleTimeout, tc.fails, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status <id>",
+	Short: "Show the details of a box",
+	Long: "Show the details of a box, including when it expires.\n" +
+		"Example: `ulacli status 42`",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireSession()
+		boxStatus(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+func boxStatus(id string) {
+	b, err := restAPI.GetBoxAPI(id)
+	if err != nil {
+		reportAPIError("", err)
+	}
+	fmt.Printf("ID:          %s\n", b.ID)
+	fmt.Printf("Image:       %s\n", b.Image)
+	fmt.Printf("IP address:  %s\n", b.IPAddress)
+	fmt.Printf("SSH port:    %s\n", b.SSHPort)
+	if b.ExpiresAt != nil {
+		fmt.Printf("Expires at:  %s\n", b.ExpiresAt.Local().Format(time.RFC1123))
+	}
+	fmt.Printf("Expires in:  %s\n", formatRemaining(b.ExpiresAt))
+}
@@ -57,6 +57,11 @@ func confirmAndSelfUpdate() {
 		log.Println("Current version is the latest")
 		return
 	}
+	if !stdinIsTerminal() {
+		// Never update unattended, an empty answer would mean yes
+		log.Println("Version", latest.Version, "is available, run `ulacli update` to install it")
+		return
+	}
 	var input string
 	fmt.Print("Do you want to update to: ", latest.Version, "? (Y/n): ")
 	fmt.Scanln(&input)
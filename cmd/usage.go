@@ -0,0 +1,101 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cypherpunkarmory/ulacli/restapi"
+	"github.com/spf13/cobra"
+)
+
+var usagePeriod string
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show how much box time you have used",
+	Long: "Show the box hours used in the current period, your running boxes against your quota\n" +
+		"and how the hours split between images.\n" +
+		"Example: `ulacli usage --period week`",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if usagePeriod != "day" && usagePeriod != "week" && usagePeriod != "month" {
+			reportError("The period has to be day, week or month", true)
+		}
+		requireSession()
+		showUsage()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.Flags().StringVar(&usagePeriod, "period", "month", "Period to report on: day, week or month")
+}
+
+func showUsage() {
+	usage, err := restAPI.Usage(usagePeriod)
+	if err != nil {
+		reportAPIError("", err)
+	}
+	if usage.StartsAt != nil && usage.EndsAt != nil {
+		fmt.Printf("Period:      %s to %s\n", usage.StartsAt.Local().Format(time.RFC1123), usage.EndsAt.Local().Format(time.RFC1123))
+	}
+	if usage.BoxHourLimit > 0 {
+		fmt.Printf("Box hours:   %.1f of %.1f\n", usage.BoxHours, usage.BoxHourLimit)
+	} else {
+		fmt.Printf("Box hours:   %.1f\n", usage.BoxHours)
+	}
+	fmt.Printf("Boxes:       %s\n", formatQuota(usage.RunningBoxes, usage.BoxLimit))
+	if len(usage.Images) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tBOXES\tBOX HOURS")
+	for _, i := range usage.Images {
+		fmt.Fprintf(w, "%s\t%d\t%.1f\n", i.Image, i.Boxes, i.BoxHours)
+	}
+	w.Flush()
+}
+
+// warnQuota warns before a box is created that goes over the quota. The API has the final
+// word, so a failed or cancelled check never stops the start.
+func warnQuota(ctx context.Context) {
+	usage, err := restAPI.UsageContext(ctx, "month")
+	if err != nil {
+		return
+	}
+	if warning := quotaWarning(usage); warning != "" {
+		reportError(warning, false)
+	}
+}
+
+// quotaWarning says why one more box goes over the quota, or is empty if it doesn't
+func quotaWarning(usage restapi.Usage) string {
+	if !usage.WouldExceed() {
+		return ""
+	}
+	if usage.BoxLimit > 0 && usage.RunningBoxes >= usage.BoxLimit {
+		return fmt.Sprintf("Warning: %d of your %d boxes are running, another one goes over your quota. `ulacli list` shows them.",
+			usage.RunningBoxes, usage.BoxLimit)
+	}
+	return fmt.Sprintf("Warning: you have used %.1f of your %.1f box hours this month", usage.BoxHours, usage.BoxHourLimit)
+}
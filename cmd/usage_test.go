@@ -0,0 +1,50 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cypherpunkarmory/ulacli/restapi"
+)
+
+func TestQuotaWarning(t *testing.T) {
+	cases := []struct {
+		name     string
+		usage    restapi.Usage
+		expected string
+	}{
+		{"RoomLeft", restapi.Usage{RunningBoxes: 1, BoxLimit: 2, BoxHours: 10, BoxHourLimit: 100}, ""},
+		{"NoLimits", restapi.Usage{RunningBoxes: 5, BoxHours: 500}, ""},
+		{"Boxes", restapi.Usage{RunningBoxes: 2, BoxLimit: 2, BoxHours: 10, BoxHourLimit: 100}, "2 of your 2 boxes are running"},
+		{"Hours", restapi.Usage{RunningBoxes: 0, BoxLimit: 2, BoxHours: 100, BoxHourLimit: 100}, "used 100.0 of your 100.0 box hours"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			warning := quotaWarning(tc.usage)
+			if tc.expected == "" && warning != "" {
+				t.Fatalf("Expected no warning, got %q", warning)
+			}
+			if !strings.Contains(warning, tc.expected) {
+				t.Fatalf("Expected a warning containing %q, got %q", tc.expected, warning)
+			}
+		})
+	}
+}
@@ -17,11 +17,18 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/cypherpunkarmory/ulacli/box"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 
@@ -43,6 +50,52 @@ func getPublicKey(path string) (string, error) {
 	return string(buf), nil
 }
 
+// sessionEnv combines the names to pass through from the local environment with
+// explicit KEY=VAL pairs. Later entries win, unset local variables are skipped.
+func sessionEnv(passEnv []string, setEnv []string) ([]string, error) {
+	var keys []string
+	values := map[string]string{}
+	add := func(key string, value string) {
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+
+	for _, key := range passEnv {
+		if !box.ValidEnvName(key) {
+			return nil, errors.New("invalid environment variable name " + key + ", use letters, digits and underscores")
+		}
+		if value, ok := os.LookupEnv(key); ok {
+			add(key, value)
+		}
+	}
+	for _, kv := range setEnv {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || !box.ValidEnvName(parts[0]) {
+			return nil, errors.New("invalid environment variable " + kv + ", use KEY=VAL with letters, digits and underscores in KEY")
+		}
+		add(parts[0], parts[1])
+	}
+
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+values[key])
+	}
+	return env, nil
+}
+
+func formatRemaining(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return "never"
+	}
+	remaining := time.Until(*expiresAt)
+	if remaining <= 0 {
+		return "expired"
+	}
+	return remaining.Round(time.Minute).String()
+}
+
 func reportError(err string, exit bool) {
 	if err == "" {
 		fmt.Fprintf(os.Stderr, "Unexpected error occured\n")
@@ -53,3 +106,54 @@ func reportError(err string, exit bool) {
 		os.Exit(1)
 	}
 }
+
+// stdinIsTerminal is false in CI and pipes, where nobody can answer a prompt
+func stdinIsTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// requireTerminal exits instead of letting a prompt block or read garbage when
+// there is no terminal, hint says how to supply the input another way
+func requireTerminal(hint string) {
+	if !stdinIsTerminal() {
+		reportError("Can't prompt for input without a terminal. "+hint, true)
+	}
+}
+
+// readHidden prompts for input that must not show on screen, such as passwords and codes
+func readHidden(prompt string, hint string) string {
+	requireTerminal(hint)
+	fmt.Print(prompt)
+	input, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		reportError("Error reading input", true)
+	}
+	return string(input)
+}
+
+// readStdinLine reads the next line of stdin for secrets that shouldn't be passed as
+// flags, where they would show up in the process list. what names it in errors.
+func readStdinLine(what string) string {
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		reportError("Error reading "+what+" from stdin", true)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		reportError("No "+what+" on stdin", true)
+	}
+	return line
+}
+
+// Shared so several lines can be read from stdin without one read buffering the next
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readLine prompts for a line of input, the user must be at a terminal
+func readLine(prompt string, hint string) (string, error) {
+	requireTerminal(hint)
+	var line string
+	fmt.Print(prompt)
+	_, err := fmt.Scanln(&line)
+	return line, err
+}
@@ -20,7 +20,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/kami-zh/go-capturer"
@@ -88,3 +90,37 @@ func TestPrintError(t *testing.T) {
 	// Test
 	// Unexpected error occured
 }
+
+func TestSessionEnv(t *testing.T) {
+	os.Setenv("ULACLI_TEST_PASS", "local value")
+	os.Unsetenv("ULACLI_TEST_UNSET")
+	cases := []struct {
+		Name       string
+		PassEnv    []string
+		SetEnv     []string
+		Expected   []string
+		ShouldFail bool
+	}{
+		{"Pass through", []string{"ULACLI_TEST_PASS", "ULACLI_TEST_UNSET"}, nil, []string{"ULACLI_TEST_PASS=local value"}, false},
+		{"Explicit value", nil, []string{"FOO=bar=baz"}, []string{"FOO=bar=baz"}, false},
+		{"Explicit wins", []string{"ULACLI_TEST_PASS"}, []string{"ULACLI_TEST_PASS=x"}, []string{"ULACLI_TEST_PASS=x"}, false},
+		{"Missing value", nil, []string{"FOO"}, nil, true},
+		{"Bad name", nil, []string{"FOO BAR=1"}, nil, true},
+		{"Command separator", nil, []string{"A;id;B=x"}, nil, true},
+		{"Command substitution", nil, []string{"$(id)=x"}, nil, true},
+		{"Leading digit", nil, []string{"1FOO=x"}, nil, true},
+		{"Pass through separator", []string{"A;id;B"}, nil, nil, true},
+		{"Pass through substitution", []string{"$(id)"}, nil, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := sessionEnv(tc.PassEnv, tc.SetEnv)
+			if (err != nil) != tc.ShouldFail {
+				t.Fatal("Failed")
+			}
+			if !reflect.DeepEqual(actual, tc.Expected) && !tc.ShouldFail {
+				t.Fatalf("Expected %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// UserLAnd Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the account you are logged in as",
+	Long: "Show the account you are logged in as, its plan and how many boxes it can run.\n" +
+		"Also a quick way to check that your saved API key still works.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireSession()
+		whoami()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+}
+
+func whoami() {
+	account, err := restAPI.Account()
+	if err != nil {
+		reportAPIError("", err)
+	}
+	confirmed := "yes"
+	if !account.Confirmed {
+		confirmed = "no, follow the link in the confirmation email"
+	}
+	fmt.Printf("Email:            %s\n", account.Email)
+	fmt.Printf("Confirmed:        %s\n", confirmed)
+	fmt.Printf("Plan:             %s\n", account.Plan)
+	fmt.Printf("Boxes:            %s\n", formatQuota(account.BoxCount, account.BoxLimit))
+	fmt.Printf("Active sessions:  %d\n", account.ActiveSessions)
+}
+
+func formatQuota(used int, limit int) string {
+	if limit == 0 {
+		return fmt.Sprintf("%d running, no limit", used)
+	}
+	return fmt.Sprintf("%d of %d running", used, limit)
+}
@@ -0,0 +1,210 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+var errWrongPassphrase = errors.New("wrong passphrase for the credential store")
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+//Passphrase Asks for the passphrase of a FileStore. create is true when the
+//store doesn't exist yet, so the passphrase is being chosen rather than entered.
+type Passphrase func(create bool) (string, error)
+
+//FileStore A Store kept in a single file encrypted with AES-GCM, using a key
+//derived from a passphrase with scrypt
+type FileStore struct {
+	Path       string
+	passphrase Passphrase
+
+	mu  sync.Mutex
+	key []byte
+}
+
+type encryptedFile struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+//NewFileStore Creates a store at path. passphrase is only asked for the first
+//time the file is read or written.
+func NewFileStore(path string, passphrase Passphrase) *FileStore {
+	return &FileStore{Path: path, passphrase: passphrase}
+}
+
+//Name Describes the store
+func (f *FileStore) Name() string {
+	return "encrypted file " + f.Path
+}
+
+//Get Looks up the secret stored under key
+func (f *FileStore) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, _, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+//Set Stores secret under key
+func (f *FileStore) Set(key string, secret string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, salt, err := f.read()
+	if err != nil {
+		return err
+	}
+	secrets[key] = secret
+	return f.write(secrets, salt)
+}
+
+//Delete Removes the secret stored under key
+func (f *FileStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, salt, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return nil
+	}
+	delete(secrets, key)
+	return f.write(secrets, salt)
+}
+
+// read decrypts the file, a missing file is an empty store with a fresh salt
+func (f *FileStore) read() (map[string]string, []byte, error) {
+	secrets := map[string]string{}
+	buf, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, 16)
+		_, err = rand.Read(salt)
+		return secrets, salt, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var file encryptedFile
+	err = json.Unmarshal(buf, &file)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := f.cipher(file.Salt, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		// The key is only kept if it decrypts, so the next attempt asks again
+		f.key = nil
+		return nil, nil, errWrongPassphrase
+	}
+	err = json.Unmarshal(plaintext, &secrets)
+	return secrets, file.Salt, err
+}
+
+func (f *FileStore) write(secrets map[string]string, salt []byte) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	// Writing without a key means read found no file, so this creates the store
+	aead, err := f.cipher(salt, f.key == nil)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(encryptedFile{
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(f.Path), 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), filepath.Base(f.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(buf)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+func (f *FileStore) cipher(salt []byte, create bool) (cipher.AEAD, error) {
+	if f.key == nil {
+		passphrase, err := f.passphrase(create)
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, errors.New("the credential store needs a passphrase")
+		}
+		f.key, err = scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+		if err != nil {
+			return nil, err
+		}
+	}
+	block, err := aes.NewCipher(f.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
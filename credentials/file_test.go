@@ -0,0 +1,118 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package credentials
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulacli-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials.enc")
+	prompts := 0
+	var created []bool
+	passphrase := func(value string) Passphrase {
+		return func(create bool) (string, error) {
+			prompts++
+			created = append(created, create)
+			return value, nil
+		}
+	}
+
+	store := NewFileStore(path, passphrase("correct horse"))
+	_, err = store.Get("apikey")
+	if err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	err = store.Set("apikey", "refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("other", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompts != 1 {
+		t.Fatalf("Expected a single passphrase prompt, got %d", prompts)
+	}
+	if !created[0] {
+		t.Fatal("The first passphrase should be asked for creating the store")
+	}
+
+	buf, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(buf), "refresh-token") {
+		t.Fatal("The secret was written in plain text")
+	}
+	info, _ := os.Stat(path)
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected a private file, got %s", info.Mode().Perm())
+	}
+
+	secret, err := NewFileStore(path, passphrase("correct horse")).Get("apikey")
+	if err != nil || secret != "refresh-token" {
+		t.Fatalf("Expected the secret back, got %q %v", secret, err)
+	}
+	_, err = NewFileStore(path, passphrase("wrong")).Get("apikey")
+	if err != errWrongPassphrase {
+		t.Fatalf("Expected errWrongPassphrase, got %v", err)
+	}
+	if created[1] || created[2] {
+		t.Fatal("Opening an existing store should not ask for a new passphrase")
+	}
+
+	err = store.Delete("apikey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get("apikey")
+	if err != ErrNotFound {
+		t.Fatalf("Expected the secret to be deleted, got %v", err)
+	}
+}
+
+func TestEnforcePrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows doesn't use unix permissions")
+	}
+	file, err := ioutil.TempFile("", "ulacli-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	_ = os.Chmod(file.Name(), 0644)
+
+	err = EnforcePrivate(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(file.Name())
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected 0600, got %s", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,88 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+//Keyring A Store backed by the Secret Service (GNOME Keyring, KWallet) through secret-tool
+type Keyring struct {
+	service string
+}
+
+//NewKeyring Stores secrets under the given service name
+func NewKeyring(service string) *Keyring {
+	return &Keyring{service: service}
+}
+
+//KeyringAvailable Reports whether secret-tool can reach a keyring daemon, it needs a session bus
+func KeyringAvailable() bool {
+	_, err := exec.LookPath("secret-tool")
+	return err == nil && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != ""
+}
+
+//Name Describes the store
+func (k *Keyring) Name() string {
+	return "system keyring"
+}
+
+//Get Looks up the secret stored under key
+func (k *Keyring) Get(key string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", k.service, "account", key)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	// A missing secret exits 1 without saying anything
+	if err != nil && stderr.Len() == 0 {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+//Set Stores secret under key, the secret goes over stdin so it never shows up in ps
+func (k *Keyring) Set(key string, secret string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "store", "--label=ulacli "+key, "service", k.service, "account", key)
+	cmd.Stdin = strings.NewReader(secret)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil && stderr.Len() > 0 {
+		return errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+//Delete Removes the secret stored under key
+func (k *Keyring) Delete(key string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "clear", "service", k.service, "account", key)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil && stderr.Len() > 0 {
+		return errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
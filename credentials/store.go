@@ -0,0 +1,67 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//ErrNotFound Nothing is stored under the key
+var ErrNotFound = errors.New("credential not found")
+
+//Store Keeps secrets such as the refresh token out of the config file
+type Store interface {
+	Get(key string) (string, error)
+	Set(key string, secret string) error
+	Delete(key string) error
+	// Name is shown to the user when telling them where their secrets went
+	Name() string
+}
+
+//FileName The encrypted file store's name in the directory passed to Open
+const FileName = "credentials.enc"
+
+//Open Returns the store of the given kind. "auto" is the system keyring. The
+//encrypted file in dir has to be asked for with "file" since it needs the
+//passphrase on every run.
+func Open(kind string, dir string, passphrase Passphrase) (Store, error) {
+	switch kind {
+	case "", "auto", "keyring":
+		if !KeyringAvailable() {
+			return nil, errors.New("no keyring is available, install secret-tool or use the file credential store")
+		}
+		return NewKeyring("ulacli"), nil
+	case "file":
+		return NewFileStore(filepath.Join(dir, FileName), passphrase), nil
+	}
+	return nil, fmt.Errorf("unknown credential store %q, use auto, keyring or file", kind)
+}
+
+//EnforcePrivate Makes sure a file holding credentials can only be read by its owner
+func EnforcePrivate(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 == 0 {
+		return nil
+	}
+	return os.Chmod(path, 0600)
+}
@@ -1,5 +1,7 @@
 module github.com/cypherpunkarmory/ulacli
 
+go 1.13
+
 require (
 	cloud.google.com/go v0.44.3 // indirect
 	github.com/ScaleFT/sshkeys v0.0.0-20181112160850-82451a803681
@@ -27,9 +29,10 @@ require (
 	github.com/tj/go-spin v1.1.0
 	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4
 	golang.org/x/mobile v0.0.0-20190806162312-597adff16ade // indirect
-	golang.org/x/net v0.0.0-20190724013045-ca1201d0de80 // indirect
+	golang.org/x/net v0.0.0-20190724013045-ca1201d0de80
 	golang.org/x/sys v0.0.0-20190813064441-fde4db37ae7a // indirect
 	golang.org/x/tools v0.0.0-20190813142322-97f12d73768f // indirect
 	google.golang.org/grpc v1.22.2 // indirect
 	honnef.co/go/tools v0.0.1-2019.2.2 // indirect
+	rsc.io/qr v0.2.0
 )
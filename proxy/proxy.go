@@ -0,0 +1,144 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	xproxy "golang.org/x/net/proxy"
+)
+
+//Func Picks the proxy for a URL, nil means connect directly.
+type Func func(*url.URL) (*url.URL, error)
+
+//FromConfig Builds a Func from an explicit proxy URL, falling back to
+//HTTPS_PROXY/HTTP_PROXY when it is empty. NO_PROXY is honoured either way.
+func FromConfig(explicit string) Func {
+	config := httpproxy.FromEnvironment()
+	if explicit != "" {
+		config.HTTPProxy = explicit
+		config.HTTPSProxy = explicit
+	}
+	return config.ProxyFunc()
+}
+
+//Dial Opens a TCP connection to addr, tunnelled through the proxy picked by
+//proxyFunc. HTTP proxies are used with CONNECT, SOCKS5 proxies directly.
+func Dial(proxyFunc Func, addr string, timeout time.Duration) (net.Conn, error) {
+	direct := &net.Dialer{Timeout: timeout}
+	if proxyFunc == nil {
+		return direct.Dial("tcp", addr)
+	}
+	// The jump host isn't HTTP but treating it as https makes HTTPS_PROXY apply to it
+	proxyURL, err := proxyFunc(&url.URL{Scheme: "https", Host: addr})
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return direct.Dial("tcp", addr)
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		dialer, err := xproxy.FromURL(proxyURL, direct)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.Dial("tcp", addr)
+	case "http", "https":
+		return dialConnect(direct, proxyURL, addr)
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+}
+
+func dialConnect(direct *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := direct.Dial("tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		err = tlsConn.Handshake()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if direct.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(direct.Timeout))
+	}
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := proxyURL.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	err = req.Write(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused connection to %s: %s", addr, resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+// bufferedConn keeps anything the proxy sent right after its response
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,126 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestFromConfig(t *testing.T) {
+	os.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	os.Setenv("NO_PROXY", "internal.example.com")
+	defer os.Unsetenv("HTTPS_PROXY")
+	defer os.Unsetenv("NO_PROXY")
+
+	cases := []struct {
+		name     string
+		explicit string
+		target   string
+		expected string
+	}{
+		{"Environment", "", "https://api.userland.tech", "http://env-proxy:3128"},
+		{"Explicit", "socks5://corp:1080", "https://api.userland.tech", "socks5://corp:1080"},
+		{"No proxy", "socks5://corp:1080", "https://internal.example.com", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, _ := url.Parse(tc.target)
+			proxyURL, err := FromConfig(tc.explicit)(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := ""
+			if proxyURL != nil {
+				actual = proxyURL.String()
+			}
+			if actual != tc.expected {
+				t.Fatalf("Expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDialConnect(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	var authorization string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Proxy-Authorization")
+		if r.Method != "CONNECT" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxyServer.Close()
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+	proxyURL.User = url.UserPassword("user", "secret")
+	conn, err := Dial(func(*url.URL) (*url.URL, error) { return proxyURL, nil }, echo.Addr().String(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("Expected ping, got %q", buf)
+	}
+	if authorization != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Fatalf("Unexpected Proxy-Authorization %q", authorization)
+	}
+}
@@ -0,0 +1,70 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/google/jsonapi"
+)
+
+//Account JSONAPI response of the account behind the current session
+type Account struct {
+	ID        string `jsonapi:"primary,user"`
+	Email     string `jsonapi:"attr,email,omitempty"`
+	Confirmed bool   `jsonapi:"attr,confirmed,omitempty"`
+	Plan      string `jsonapi:"attr,plan,omitempty"`
+	// BoxLimit is how many boxes the plan allows at once, zero means no limit
+	BoxLimit       int `jsonapi:"attr,boxLimit,omitempty"`
+	BoxCount       int `jsonapi:"attr,boxCount,omitempty"`
+	ActiveSessions int `jsonapi:"attr,activeSessions,omitempty"`
+}
+
+//Account fetches the account the session belongs to
+func (restClient *RestClient) Account() (Account, error) {
+	return restClient.AccountContext(context.Background())
+}
+
+//AccountContext Account that gives up when ctx is done
+func (restClient *RestClient) AccountContext(ctx context.Context) (Account, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	account := Account{}
+	req, err := http.NewRequest("GET", restClient.URL+"/account", nil)
+	if err != nil {
+		return account, errorCantConnectRestCall
+	}
+	resp, err := restClient.do(ctx, req)
+	if err != nil {
+		return account, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		buf, _ := ioutil.ReadAll(resp.Body)
+		return account, responseError(resp.StatusCode, buf)
+	}
+
+	err = jsonapi.UnmarshalPayload(resp.Body, &account)
+	if err != nil {
+		return account, errorUnableToParse
+	}
+	return account, nil
+}
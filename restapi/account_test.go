@@ -0,0 +1,47 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/account" || r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data": {"type": "user", "id": "7", "attributes": {"email": "me@example.com", "confirmed": true, "plan": "free", "boxLimit": 2, "boxCount": 1, "activeSessions": 3}}}`))
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	restClient.SetAPIKey("access")
+	account, err := restClient.Account()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Account{ID: "7", Email: "me@example.com", Confirmed: true, Plan: "free", BoxLimit: 2, BoxCount: 1, ActiveSessions: 3}
+	if account != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, account)
+	}
+}
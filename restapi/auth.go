@@ -0,0 +1,191 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type noAuthKey struct{}
+
+// How long an access token is trusted when the API doesn't say. Guessing
+// too long only costs a 401 and a refresh.
+const assumedTokenLifetime = 15 * time.Minute
+
+// authTransport adds the access token to every request. When the API answers
+// 401 it exchanges the refresh token for a new access token once and replays
+// the request. Concurrent refreshes are serialized so only one hits /session.
+type authTransport struct {
+	url string
+	rt  http.RoundTripper
+
+	mu             sync.Mutex
+	accessToken    string
+	expiresAt      time.Time
+	refreshToken   string
+	requestTimeout time.Duration
+	cache          TokenCache
+}
+
+// withoutAuth marks requests made with ctx to go out as they are, without a token or a refresh on 401
+func withoutAuth(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noAuthKey{}, true)
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(noAuthKey{}) != nil || req.Header.Get("Authorization") != "" {
+		return t.rt.RoundTrip(req)
+	}
+
+	t.mu.Lock()
+	accessToken := t.accessToken
+	t.mu.Unlock()
+
+	resp, err := t.rt.RoundTrip(authorize(req, accessToken))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// Without GetBody the body is already consumed and can't be sent again
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	newToken, err := t.refresh(req.Context(), accessToken)
+	if err != nil {
+		return resp, nil
+	}
+	retry := authorize(req, newToken)
+	if req.Body != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return t.rt.RoundTrip(retry)
+}
+
+// refresh gets a new access token unless another request already replaced
+// stale while this one was waiting for the lock
+func (t *authTransport) refresh(ctx context.Context, stale string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.accessToken != stale {
+		return t.accessToken, nil
+	}
+	err := t.startSession(ctx)
+	return t.accessToken, err
+}
+
+// ensureToken gets an access token before a request needs one, from the cache
+// when possible. Without a refresh token requests go out with whatever we have.
+func (t *authTransport) ensureToken(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.accessToken != "" && (t.expiresAt.IsZero() || time.Until(t.expiresAt) > tokenExpirySkew) {
+		return nil
+	}
+	if t.refreshToken == "" {
+		return nil
+	}
+	if t.cache != nil {
+		if cached, ok := t.cache.Get(tokenKey(t.url, t.refreshToken)); ok {
+			t.accessToken, t.expiresAt = cached.AccessToken, cached.ExpiresAt
+			return nil
+		}
+	}
+	return t.startSession(ctx)
+}
+
+// useSession stores the tokens from a session response, t.mu must be held
+func (t *authTransport) useSession(session SessionResponse) {
+	if session.RefreshToken != "" {
+		t.refreshToken = session.RefreshToken
+	}
+	t.accessToken = session.AccessToken
+	lifetime := time.Duration(session.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = assumedTokenLifetime
+	}
+	t.expiresAt = time.Now().Add(lifetime)
+	if t.cache == nil {
+		return
+	}
+	// A cache we can't write only costs a session next time
+	_ = t.cache.Set(tokenKey(t.url, t.refreshToken), CachedToken{AccessToken: t.accessToken, ExpiresAt: t.expiresAt})
+}
+
+// forget drops every token, including the cached access token
+func (t *authTransport) forget() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cache != nil && t.refreshToken != "" {
+		_ = t.cache.Delete(tokenKey(t.url, t.refreshToken))
+	}
+	t.accessToken, t.refreshToken, t.expiresAt = "", "", time.Time{}
+}
+
+// startSession exchanges the refresh token for an access token, t.mu must be held
+func (t *authTransport) startSession(ctx context.Context) error {
+	if t.refreshToken == "" {
+		return errorNoRefreshToken
+	}
+	if t.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.requestTimeout)
+		defer cancel()
+	}
+	responseBody := SessionResponse{}
+	req, err := http.NewRequest("PUT", t.url+"/session", nil)
+	if err != nil {
+		return errorCantConnectRestCall
+	}
+	req.Header.Add("Authorization", "Bearer "+t.refreshToken)
+	resp, err := t.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return connectionError(ctx, err)
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode > 399 {
+		return responseError(resp.StatusCode, body)
+	}
+
+	err = json.Unmarshal(body, &responseBody)
+	if err != nil {
+		return errorUnableToParse
+	}
+	// /session answers with the access token only, keep the refresh token we used
+	responseBody.RefreshToken = ""
+	t.useSession(responseBody)
+	return nil
+}
+
+func authorize(req *http.Request, accessToken string) *http.Request {
+	authorized := req.Clone(req.Context())
+	if accessToken != "" {
+		authorized.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return authorized
+}
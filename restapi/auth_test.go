@@ -0,0 +1,321 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshOnUnauthorized(t *testing.T) {
+	var sessions int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/session":
+			if r.Header.Get("Authorization") != "Bearer refresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			atomic.AddInt32(&sessions, 1)
+			w.Write([]byte(`{"access_token": "fresh"}`))
+		case r.Header.Get("Authorization") != "Bearer fresh":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "refresh")
+	restClient.SetAPIKey("expired")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- restClient.DeleteBoxAPI("1")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Request should succeed after a refresh: %s", err)
+		}
+	}
+	if sessions != 1 {
+		t.Fatalf("Expected a single refresh, got %d", sessions)
+	}
+}
+
+func TestNoRefreshOnLogin(t *testing.T) {
+	var sessions int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/session" {
+			atomic.AddInt32(&sessions, 1)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"data": {"attributes": {"detail": "bad password"}}}`))
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "refresh")
+	_, err := restClient.Login("user", "wrong")
+	if err == nil || err.Error() != "bad password" {
+		t.Fatalf("Expected the login error, got %v", err)
+	}
+	if sessions != 0 {
+		t.Fatal("Login should never refresh the session")
+	}
+}
+
+func TestTokenCache(t *testing.T) {
+	var sessions int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/session" {
+			atomic.AddInt32(&sessions, 1)
+			w.Write([]byte(`{"access_token": "fresh", "expires-in": 3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "ulacli-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.json")
+
+	// Every client stands in for a separate run of ulacli
+	for i := 0; i < 3; i++ {
+		restClient := NewRestClient(server.URL, "refresh", WithTokenCache(NewFileTokenCache(path)))
+		err = restClient.DeleteBoxAPI("1")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sessions != 1 {
+		t.Fatalf("Expected a single session for all runs, got %d", sessions)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the cache to be private, got %s", info.Mode().Perm())
+	}
+
+	// A different refresh token must not pick up someone else's access token
+	restClient := NewRestClient(server.URL, "other", WithTokenCache(NewFileTokenCache(path)))
+	_ = restClient.DeleteBoxAPI("1")
+	if sessions != 2 {
+		t.Fatalf("Expected a new session for another refresh token, got %d", sessions)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	cases := []struct {
+		name     string
+		response string
+		lifetime time.Duration
+	}{
+		{"expires-in", `{"access_token": "fresh", "expires-in": 3600}`, time.Hour},
+		{"expires_in", `{"access_token": "fresh", "expires_in": 1800}`, 30 * time.Minute},
+		{"No expiry", `{"access_token": "fresh"}`, assumedTokenLifetime},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/session" {
+					w.Write([]byte(tc.response))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			dir, err := ioutil.TempDir("", "ulacli-session")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			cache := NewFileTokenCache(filepath.Join(dir, "session.json"))
+
+			restClient := NewRestClient(server.URL, "refresh", WithTokenCache(cache))
+			err = restClient.StartSession("refresh")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cached, ok := cache.Get(tokenKey(server.URL, "refresh"))
+			if !ok || cached.AccessToken != "fresh" {
+				t.Fatal("Expected the access token to be cached")
+			}
+			remaining := time.Until(cached.ExpiresAt)
+			if remaining > tc.lifetime || remaining < tc.lifetime-time.Minute {
+				t.Fatalf("Expected the token to expire in %s, got %s", tc.lifetime, remaining)
+			}
+		})
+	}
+}
+
+func TestFileTokenCacheExpiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ulacli-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := NewFileTokenCache(filepath.Join(dir, "session.json"))
+
+	_ = cache.Set("soon", CachedToken{AccessToken: "a", ExpiresAt: time.Now().Add(10 * time.Second)})
+	_ = cache.Set("later", CachedToken{AccessToken: "b", ExpiresAt: time.Now().Add(time.Hour)})
+	if _, ok := cache.Get("soon"); ok {
+		t.Fatal("A token about to expire should not be used")
+	}
+	if token, ok := cache.Get("later"); !ok || token.AccessToken != "b" {
+		t.Fatal("Expected the valid token")
+	}
+	_ = cache.Delete("later")
+	if _, ok := cache.Get("later"); ok {
+		t.Fatal("Expected the token to be deleted")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.String()+" "+r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "refresh")
+	restClient.SetAPIKey("access")
+	err := restClient.Logout(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restClient.SetRefreshToken("other")
+	err = restClient.Logout(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"DELETE /session Bearer refresh", "DELETE /session?all=true Bearer other"}
+	if len(requests) != 2 || requests[0] != expected[0] || requests[1] != expected[1] {
+		t.Fatalf("Expected %v, got %v", expected, requests)
+	}
+	if restClient.Logout(false) != errorNoRefreshToken {
+		t.Fatal("Logging out should forget the refresh token")
+	}
+}
+
+func TestSignup(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		body = string(buf)
+		if r.Method != "POST" || r.URL.Path != "/account" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	err := restClient.Signup("me@example.com", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"type":"user"`, `"email":"me@example.com"`, `"password":"hunter2"`} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %s in %s", expected, body)
+		}
+	}
+}
+
+func TestPasswordRequests(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization")+" "+string(buf))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	restClient.SetAPIKey("access")
+	_ = restClient.RequestPasswordReset("me@example.com")
+	_ = restClient.ResetPassword("code", "new")
+	_ = restClient.ChangePassword("old", "new")
+
+	expected := []string{
+		`POST /account/password_reset  {"data":{"type":"password_reset","attributes":{"email":"me@example.com"}}}`,
+		`PUT /account/password_reset  {"data":{"type":"password_reset","attributes":{"password":"new","token":"code"}}}`,
+		`PATCH /account/password Bearer access {"data":{"type":"password","attributes":{"currentPassword":"old","newPassword":"new"}}}`,
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected %d requests, got %v", len(expected), requests)
+	}
+	for i := range expected {
+		if strings.TrimSpace(requests[i]) != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], requests[i])
+		}
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(buf), `"otp":"123456"`) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": [{"status": "401", "code": "mfa_required", "detail": "Two-factor code required"}]}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "access", "refresh_token": "refresh"}`))
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	_, err := restClient.Login("me@example.com", "hunter2")
+	if !errors.Is(err, ErrMFARequired) {
+		t.Fatalf("Expected ErrMFARequired, got %v", err)
+	}
+	response, err := restClient.LoginMFA("me@example.com", "hunter2", "123456")
+	if err != nil || response.RefreshToken != "refresh" {
+		t.Fatalf("Expected to log in with the code, got %v", err)
+	}
+}
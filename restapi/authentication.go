@@ -19,9 +19,11 @@ package restapi
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/google/jsonapi"
 )
@@ -34,9 +36,34 @@ type SessionResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+//UnmarshalJSON Accepts the OAuth expires_in as well as expires-in
+func (session *SessionResponse) UnmarshalJSON(data []byte) error {
+	type plain SessionResponse
+	var response struct {
+		plain
+		OAuthExpiresIn int `json:"expires_in"`
+	}
+	err := json.Unmarshal(data, &response)
+	if err != nil {
+		return err
+	}
+	*session = SessionResponse(response.plain)
+	if session.ExpiresIn == 0 {
+		session.ExpiresIn = response.OAuthExpiresIn
+	}
+	return nil
+}
+
 type loginRequest struct {
 	Username string `json:"email"`
 	Password string `json:"password"`
+	OTP      string `json:"otp,omitempty"`
+}
+
+type signupRequest struct {
+	ID       string `jsonapi:"primary,user"`
+	Email    string `jsonapi:"attr,email"`
+	Password string `jsonapi:"attr,password"`
 }
 
 type resendRequest struct {
@@ -44,59 +71,68 @@ type resendRequest struct {
 	Email string `jsonapi:"attr,email"`
 }
 
-//StartSession Start a session and set the restClient to the current access token
+//StartSession Start a session and set the restClient to the current access token.
+//A cached access token that is still valid is reused instead without contacting
+//the API, so a revoked refresh token or an outdated client is only reported by
+//the next request that needs the session.
 func (restClient *RestClient) StartSession(refreshToken string) error {
-	responseBody := SessionResponse{}
-	url := restClient.URL + "/session"
-	req, err := http.NewRequest("PUT", url, nil)
-	if err != nil {
-		return errorCantConnectRestCall
-	}
-	req.Header.Add("Authorization", "Bearer "+refreshToken)
-	client := http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return errorCantConnectRestCall
-	}
-
-	defer resp.Body.Close()
+	return restClient.StartSessionContext(context.Background(), refreshToken)
+}
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	if resp.StatusCode > 399 {
-		errorBody := ResponseError{}
-		err = json.Unmarshal(body, &errorBody)
-		if err != nil {
-			return err
-		}
-		return &errorBody
+//StartSessionContext StartSession that gives up when ctx is done
+func (restClient *RestClient) StartSessionContext(ctx context.Context, refreshToken string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	restClient.auth.mu.Lock()
+	if restClient.auth.refreshToken != refreshToken {
+		// The access token we hold belongs to another refresh token
+		restClient.auth.accessToken = ""
+		restClient.auth.expiresAt = time.Time{}
+		restClient.auth.refreshToken = refreshToken
 	}
-
-	err = json.Unmarshal(body, &responseBody)
-	if err != nil {
-		return errorUnableToParse
-	}
-	restClient.SetAPIKey(responseBody.AccessToken)
-	return nil
+	restClient.auth.mu.Unlock()
+	return restClient.auth.ensureToken(ctx)
 }
 
 //Login Login user with given username and password. Returns sessionresponse so cmd can set viper configs
 func (restClient *RestClient) Login(username string, password string) (SessionResponse, error) {
+	return restClient.LoginContext(context.Background(), username, password)
+}
+
+//LoginContext Login that gives up when ctx is done
+func (restClient *RestClient) LoginContext(ctx context.Context, username string, password string) (SessionResponse, error) {
+	return restClient.LoginMFAContext(ctx, username, password, "")
+}
+
+//LoginMFA Login with a two-factor authentication code, needed once Login fails with ErrMFARequired
+func (restClient *RestClient) LoginMFA(username string, password string, otp string) (SessionResponse, error) {
+	return restClient.LoginMFAContext(context.Background(), username, password, otp)
+}
+
+//LoginMFAContext LoginMFA that gives up when ctx is done
+func (restClient *RestClient) LoginMFAContext(ctx context.Context, username string, password string, otp string) (SessionResponse, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
 	responseBody := SessionResponse{}
 	url := restClient.URL + "/login"
 
 	reqBody := loginRequest{
 		Username: username,
 		Password: password,
+		OTP:      otp,
 	}
 	jsonStr, _ := json.Marshal(&reqBody)
 
 	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonStr))
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := restClient.Client.Do(req)
+	// A 401 here means bad credentials, not an expired token
+	resp, err := restClient.do(withoutAuth(ctx), req)
 
 	if err != nil {
-		return responseBody, errorCantConnectRestCall
+		return responseBody, err
 	}
 
 	defer resp.Body.Close()
@@ -104,25 +140,88 @@ func (restClient *RestClient) Login(username string, password string) (SessionRe
 	body, _ := ioutil.ReadAll(resp.Body)
 
 	if resp.StatusCode > 399 {
-		errorBody := ResponseError{}
-		err = json.Unmarshal(body, &errorBody)
-		if err != nil {
-			return responseBody, err
-		}
-		return responseBody, &errorBody
+		return responseBody, responseError(resp.StatusCode, body)
 	}
 
 	err = json.Unmarshal(body, &responseBody)
 	if err != nil {
 		return responseBody, errorUnableToParse
 	}
-	restClient.RefreshToken = responseBody.RefreshToken
-	restClient.SetAPIKey(responseBody.AccessToken)
+	restClient.auth.mu.Lock()
+	restClient.auth.useSession(responseBody)
+	restClient.auth.mu.Unlock()
 	return responseBody, nil
 }
 
+//Logout Revokes the refresh token so it can't start sessions anymore, with all
+//every session of the account is revoked
+func (restClient *RestClient) Logout(all bool) error {
+	return restClient.LogoutContext(context.Background(), all)
+}
+
+//LogoutContext Logout that gives up when ctx is done
+func (restClient *RestClient) LogoutContext(ctx context.Context, all bool) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	restClient.auth.mu.Lock()
+	refreshToken := restClient.auth.refreshToken
+	restClient.auth.mu.Unlock()
+	if refreshToken == "" {
+		return errorNoRefreshToken
+	}
+
+	url := restClient.URL + "/session"
+	if all {
+		url += "?all=true"
+	}
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return errorCantConnectRestCall
+	}
+	// The refresh token is what gets revoked, a 401 means it already was
+	req.Header.Add("Authorization", "Bearer "+refreshToken)
+	resp, err := restClient.do(withoutAuth(ctx), req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode > 399 {
+		return responseError(resp.StatusCode, body)
+	}
+	restClient.auth.forget()
+	return nil
+}
+
+//Signup Creates an account. The email address has to be confirmed before the account can log in.
+func (restClient *RestClient) Signup(email string, password string) error {
+	return restClient.SignupContext(context.Background(), email, password)
+}
+
+//SignupContext Signup that gives up when ctx is done
+func (restClient *RestClient) SignupContext(ctx context.Context, email string, password string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	reqBody := signupRequest{
+		Email:    email,
+		Password: password,
+	}
+	return restClient.sendPayload(withoutAuth(ctx), "POST", "/account", &reqBody)
+}
+
 //ResendConfirmationEmail If user is unconfirmed this will resend a confirmation email
 func (restClient *RestClient) ResendConfirmationEmail(email string) error {
+	return restClient.ResendConfirmationEmailContext(context.Background(), email)
+}
+
+//ResendConfirmationEmailContext ResendConfirmationEmail that gives up when ctx is done
+func (restClient *RestClient) ResendConfirmationEmailContext(ctx context.Context, email string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
 	url := restClient.URL + "/account/token"
 
 	reqBody := resendRequest{
@@ -137,10 +236,11 @@ func (restClient *RestClient) ResendConfirmationEmail(email string) error {
 	req, _ := http.NewRequest("POST", url, &outputBuffer)
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := restClient.Client.Do(req)
+	// Unconfirmed accounts can't start a session, so this goes out without one
+	resp, err := restClient.do(withoutAuth(ctx), req)
 
 	if err != nil {
-		return errorCantConnectRestCall
+		return err
 	}
 
 	defer resp.Body.Close()
@@ -148,12 +248,7 @@ func (restClient *RestClient) ResendConfirmationEmail(email string) error {
 	body, _ := ioutil.ReadAll(resp.Body)
 
 	if resp.StatusCode > 399 {
-		errorBody := ResponseError{}
-		err = json.Unmarshal(body, &errorBody)
-		if err != nil {
-			return err
-		}
-		return &errorBody
+		return responseError(resp.StatusCode, body)
 	}
 	return nil
 }
@@ -19,9 +19,11 @@ package restapi
 import (
 	"bufio"
 	"bytes"
-	"encoding/json"
+	"context"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"time"
 
 	"github.com/google/jsonapi"
 )
@@ -34,17 +36,29 @@ type Box struct {
 	Image     string     `jsonapi:"attr,image,omitempty"`
 	SSHPort   string     `jsonapi:"attr,sshPort,omitempty"`
 	IPAddress string     `jsonapi:"attr,ipAddress,omitempty"`
-	Config *Config `jsonapi:"relation,config,omitempty"`
+	TTL       int        `jsonapi:"attr,ttl,omitempty"`
+	ExpiresAt *time.Time `jsonapi:"attr,expiresAt,iso8601,omitempty"`
+	Config    *Config    `jsonapi:"relation,config,omitempty"`
 }
 
-//CreateBoxAPI calls UserLAnd Cloud web api to get box details
-func (restClient *RestClient) CreateBoxAPI(publicKey string, image string) (Box, error) {
+//CreateBoxAPI calls UserLAnd Cloud web api to get box details.
+//A ttl above zero asks the API to delete the box once it expires.
+func (restClient *RestClient) CreateBoxAPI(publicKey string, image string, ttl time.Duration) (Box, error) {
+	return restClient.CreateBoxAPIContext(context.Background(), publicKey, image, ttl)
+}
+
+//CreateBoxAPIContext CreateBoxAPI that gives up when ctx is done
+func (restClient *RestClient) CreateBoxAPIContext(ctx context.Context, publicKey string, image string, ttl time.Duration) (Box, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
 	boxReturn := Box{}
 	var outputBuffer bytes.Buffer
 
 	request := Box{
 		PublicKey: publicKey,
-		Image: image,
+		Image:     image,
+		TTL:       int(ttl.Seconds()),
 	}
 
 	_ = bufio.NewWriter(&outputBuffer)
@@ -59,20 +73,17 @@ func (restClient *RestClient) CreateBoxAPI(publicKey string, image string) (Box,
 		return boxReturn, errorCantConnectRestCall
 	}
 	req.Header.Add("Content-Type", "application/json")
-	resp, err := restClient.Client.Do(req)
+	// Lets a retried create return the box from the first attempt instead of a second one
+	req.Header.Set("Idempotency-Key", randomID())
+	resp, err := restClient.do(ctx, req)
 	if err != nil {
-		return boxReturn, errorCantConnectRestCall
+		return boxReturn, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode > 399 {
 		buf, _ := ioutil.ReadAll(resp.Body)
-		errObject := ResponseError{}
-		err = json.Unmarshal(buf, &errObject)
-		if err != nil {
-			return boxReturn, err
-		}
-		return boxReturn, &errObject
+		return boxReturn, responseError(resp.StatusCode, buf)
 	}
 
 	err = jsonapi.UnmarshalPayload(resp.Body, &boxReturn)
@@ -84,15 +95,22 @@ func (restClient *RestClient) CreateBoxAPI(publicKey string, image string) (Box,
 
 //DeleteBoxAPI deletes box
 func (restClient *RestClient) DeleteBoxAPI(boxId string) error {
+	return restClient.DeleteBoxAPIContext(context.Background(), boxId)
+}
+
+//DeleteBoxAPIContext DeleteBoxAPI that gives up when ctx is done
+func (restClient *RestClient) DeleteBoxAPIContext(ctx context.Context, boxId string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
 
 	url := restClient.URL + "/boxes/" + boxId
 	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return errorCantConnectRestCall
 	}
-	resp, err := restClient.Client.Do(req)
+	resp, err := restClient.do(ctx, req)
 	if err != nil {
-		return errorCantConnectRestCall
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -102,13 +120,128 @@ func (restClient *RestClient) DeleteBoxAPI(boxId string) error {
 
 	body, _ := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode > 399 {
-		errorBody := ResponseError{}
-		err = json.Unmarshal(body, &errorBody)
-		if err != nil {
-			return err
-		}
-		return &errorBody
+		return responseError(resp.StatusCode, body)
 	}
 
 	return errorUnableToDelete
 }
+
+//GetBoxAPI fetches the details of a single box
+func (restClient *RestClient) GetBoxAPI(boxID string) (Box, error) {
+	return restClient.GetBoxAPIContext(context.Background(), boxID)
+}
+
+//GetBoxAPIContext GetBoxAPI that gives up when ctx is done
+func (restClient *RestClient) GetBoxAPIContext(ctx context.Context, boxID string) (Box, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	boxReturn := Box{}
+
+	url := restClient.URL + "/boxes/" + boxID
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return boxReturn, errorCantConnectRestCall
+	}
+	resp, err := restClient.do(ctx, req)
+	if err != nil {
+		return boxReturn, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		buf, _ := ioutil.ReadAll(resp.Body)
+		return boxReturn, responseError(resp.StatusCode, buf)
+	}
+
+	err = jsonapi.UnmarshalPayload(resp.Body, &boxReturn)
+	if err != nil {
+		return boxReturn, errorUnableToParse
+	}
+	return boxReturn, nil
+}
+
+//ListBoxesAPI lists every box on the account
+func (restClient *RestClient) ListBoxesAPI() ([]Box, error) {
+	return restClient.ListBoxesAPIContext(context.Background())
+}
+
+//ListBoxesAPIContext ListBoxesAPI that gives up when ctx is done
+func (restClient *RestClient) ListBoxesAPIContext(ctx context.Context) ([]Box, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	url := restClient.URL + "/boxes"
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, errorCantConnectRestCall
+	}
+	resp, err := restClient.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		buf, _ := ioutil.ReadAll(resp.Body)
+		return nil, responseError(resp.StatusCode, buf)
+	}
+
+	payload, err := jsonapi.UnmarshalManyPayload(resp.Body, reflect.TypeOf(new(Box)))
+	if err != nil {
+		return nil, errorUnableToParse
+	}
+	boxes := make([]Box, 0, len(payload))
+	for _, item := range payload {
+		boxes = append(boxes, *item.(*Box))
+	}
+	return boxes, nil
+}
+
+//ExtendBoxAPI moves the expiry of a box to expiresAt
+func (restClient *RestClient) ExtendBoxAPI(boxID string, expiresAt time.Time) (Box, error) {
+	return restClient.ExtendBoxAPIContext(context.Background(), boxID, expiresAt)
+}
+
+//ExtendBoxAPIContext ExtendBoxAPI that gives up when ctx is done
+func (restClient *RestClient) ExtendBoxAPIContext(ctx context.Context, boxID string, expiresAt time.Time) (Box, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	boxReturn := Box{}
+	var outputBuffer bytes.Buffer
+
+	request := Box{
+		ID:        boxID,
+		ExpiresAt: &expiresAt,
+	}
+
+	_ = bufio.NewWriter(&outputBuffer)
+	err := jsonapi.MarshalPayload(&outputBuffer, &request)
+	if err != nil {
+		return boxReturn, errorUnableToParse
+	}
+
+	url := restClient.URL + "/boxes/" + boxID
+	req, err := http.NewRequest("PATCH", url, &outputBuffer)
+	if err != nil {
+		return boxReturn, errorCantConnectRestCall
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := restClient.do(ctx, req)
+	if err != nil {
+		return boxReturn, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		buf, _ := ioutil.ReadAll(resp.Body)
+		return boxReturn, responseError(resp.StatusCode, buf)
+	}
+
+	err = jsonapi.UnmarshalPayload(resp.Body, &boxReturn)
+	if err != nil {
+		return boxReturn, errorUnableToParse
+	}
+	return boxReturn, nil
+}
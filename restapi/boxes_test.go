@@ -0,0 +1,127 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const boxJSON = `{"type": "box", "id": "42", "attributes": {"image": "ubuntu", "sshPort": "2222", "ipAddress": "10.0.0.1", "ttl": 3600, "expiresAt": "2026-10-19T12:00:00Z"}}`
+
+func TestCreateBoxTTL(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		body = string(buf)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data": ` + boxJSON + `}`))
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	restClient.SetAPIKey("access")
+	_, err := restClient.CreateBoxAPI("key", "ubuntu", 90*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, `"ttl":5400`) {
+		t.Fatalf("Expected the ttl in seconds, got %s", body)
+	}
+}
+
+func TestGetBox(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/boxes/42" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": [{"status": "404", "detail": "No such box"}]}`))
+			return
+		}
+		w.Write([]byte(`{"data": ` + boxJSON + `}`))
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	restClient.SetAPIKey("access")
+	b, err := restClient.GetBoxAPI("42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if b.ID != "42" || b.SSHPort != "2222" || b.TTL != 3600 || b.ExpiresAt == nil || !b.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("Unexpected box %+v", b)
+	}
+
+	_, err = restClient.GetBoxAPI("7")
+	if err == nil || err.Error() != "No such box" {
+		t.Fatalf("Expected the API error, got %v", err)
+	}
+}
+
+func TestListBoxes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/boxes" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data": [` + boxJSON + `, {"type": "box", "id": "43", "attributes": {"image": "kali"}}]}`))
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	restClient.SetAPIKey("access")
+	boxes, err := restClient.ListBoxesAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boxes) != 2 || boxes[0].ID != "42" || boxes[1].Image != "kali" || boxes[1].ExpiresAt != nil {
+		t.Fatalf("Unexpected boxes %+v", boxes)
+	}
+}
+
+func TestExtendBox(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		body = string(buf)
+		if r.Method != "PATCH" || r.URL.Path != "/boxes/42" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data": ` + boxJSON + `}`))
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	restClient.SetAPIKey("access")
+	b, err := restClient.ExtendBoxAPI("42", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, `"id":"42"`) || !strings.Contains(body, `"expiresAt":"2026-10-19T12:00:00Z"`) {
+		t.Fatalf("Unexpected request %s", body)
+	}
+	if b.ID != "42" {
+		t.Fatalf("Unexpected box %+v", b)
+	}
+}
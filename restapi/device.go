@@ -0,0 +1,219 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cypherpunkarmory/ulacli/backoff"
+)
+
+const deviceClientID = "ulacli"
+
+const deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// RFC 8628 counts intervals in seconds and says to poll every 5 when the server doesn't say otherwise
+const defaultDeviceInterval = 5
+
+// deviceIntervalUnit is shortened by tests
+var deviceIntervalUnit = time.Second
+
+var (
+	//ErrDeviceDenied The user declined the device login
+	ErrDeviceDenied = errors.New("the login was denied")
+	//ErrDeviceExpired The user code expired before the login was approved
+	ErrDeviceExpired = errors.New("the login code expired")
+)
+
+//DeviceAuthorization The codes for a device login (RFC 8628). The user opens
+//VerificationURI and enters UserCode while ulacli polls with DeviceCode.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type deviceTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Error        string `json:"error"`
+}
+
+//StartDeviceLogin Asks for a user code to approve this device in a browser
+func (restClient *RestClient) StartDeviceLogin() (DeviceAuthorization, error) {
+	return restClient.StartDeviceLoginContext(context.Background())
+}
+
+//StartDeviceLoginContext StartDeviceLogin that gives up when ctx is done
+func (restClient *RestClient) StartDeviceLoginContext(ctx context.Context) (DeviceAuthorization, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	authorization := DeviceAuthorization{}
+	form := url.Values{"client_id": {deviceClientID}}
+	resp, err := restClient.postForm(ctx, "/oauth/device_authorization", form)
+	if err != nil {
+		return authorization, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode > 399 {
+		return authorization, responseError(resp.StatusCode, body)
+	}
+	err = json.Unmarshal(body, &authorization)
+	if err != nil || authorization.DeviceCode == "" {
+		return authorization, errorUnableToParse
+	}
+	return authorization, nil
+}
+
+//WaitForDeviceLogin Polls until the user approves or denies the login, or the code expires
+func (restClient *RestClient) WaitForDeviceLogin(authorization DeviceAuthorization) (SessionResponse, error) {
+	return restClient.WaitForDeviceLoginContext(context.Background(), authorization)
+}
+
+//WaitForDeviceLoginContext WaitForDeviceLogin that gives up when ctx is done.
+//The overall API timeout doesn't apply since the user may take a while.
+func (restClient *RestClient) WaitForDeviceLoginContext(ctx context.Context, authorization DeviceAuthorization) (SessionResponse, error) {
+	if authorization.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(authorization.ExpiresIn)*deviceIntervalUnit)
+		defer cancel()
+	}
+	interval := defaultDeviceInterval * deviceIntervalUnit
+	if authorization.Interval > 0 {
+		interval = time.Duration(authorization.Interval) * deviceIntervalUnit
+	}
+	// Network trouble backs off on top of the interval the server asked for
+	exponentialBackoff := backoff.NewExponentialBackOff()
+	exponentialBackoff.InitialInterval = interval
+	exponentialBackoff.MaxInterval = 4 * interval
+	exponentialBackoff.Reset()
+
+	form := url.Values{
+		"grant_type":  {deviceGrantType},
+		"device_code": {authorization.DeviceCode},
+		"client_id":   {deviceClientID},
+	}
+	wait := interval
+	for {
+		err := sleepContext(ctx, wait)
+		if err == context.DeadlineExceeded {
+			return SessionResponse{}, ErrDeviceExpired
+		}
+		if err != nil {
+			return SessionResponse{}, err
+		}
+
+		token, err := restClient.pollDeviceToken(ctx, form)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return SessionResponse{}, err
+		}
+		if err != nil {
+			// The device code's expiry bounds the loop, so keep polling at the slowest rate
+			wait = exponentialBackoff.NextBackOff()
+			if wait == backoff.Stop {
+				wait = exponentialBackoff.MaxInterval
+			}
+			continue
+		}
+		exponentialBackoff.Reset()
+		wait = interval
+
+		switch token.Error {
+		case "":
+			session := SessionResponse{
+				AccessToken:  token.AccessToken,
+				TokenType:    token.TokenType,
+				ExpiresIn:    token.ExpiresIn,
+				RefreshToken: token.RefreshToken,
+			}
+			restClient.auth.mu.Lock()
+			restClient.auth.useSession(session)
+			restClient.auth.mu.Unlock()
+			return session, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * deviceIntervalUnit
+			wait = interval
+		case "access_denied":
+			return SessionResponse{}, ErrDeviceDenied
+		case "expired_token":
+			return SessionResponse{}, ErrDeviceExpired
+		default:
+			return SessionResponse{}, &APIError{StatusCode: http.StatusBadRequest, Code: token.Error, Detail: token.Error}
+		}
+	}
+}
+
+// pollDeviceToken returns the token response, which carries an error code
+// while the login is pending
+func (restClient *RestClient) pollDeviceToken(ctx context.Context, form url.Values) (deviceTokenResponse, error) {
+	token := deviceTokenResponse{}
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := restClient.postForm(ctx, "/oauth/token", form)
+	if err != nil {
+		return token, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	err = json.Unmarshal(body, &token)
+	if resp.StatusCode > 399 && (err != nil || token.Error == "") {
+		return token, responseError(resp.StatusCode, body)
+	}
+	if err != nil {
+		return token, errorUnableToParse
+	}
+	return token, nil
+}
+
+func (restClient *RestClient) postForm(ctx context.Context, path string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequest("POST", restClient.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errorCantConnectRestCall
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	return restClient.do(withoutAuth(ctx), req)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,111 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeviceLogin(t *testing.T) {
+	deviceIntervalUnit = time.Millisecond
+	defer func() { deviceIntervalUnit = time.Second }()
+
+	cases := []struct {
+		name      string
+		responses []string
+		expected  error
+	}{
+		{"Approved", []string{"authorization_pending", "slow_down", ""}, nil},
+		{"Denied", []string{"authorization_pending", "access_denied"}, ErrDeviceDenied},
+		{"Expired", []string{"expired_token"}, ErrDeviceExpired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var polls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				switch r.URL.Path {
+				case "/oauth/device_authorization":
+					w.Write([]byte(`{"device_code": "device", "user_code": "ABCD-EFGH", "verification_uri": "https://userland.tech/device", "expires_in": 1000, "interval": 1}`))
+				case "/oauth/token":
+					if r.PostForm.Get("device_code") != "device" || r.PostForm.Get("grant_type") != deviceGrantType {
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(`{"error": "invalid_request"}`))
+						return
+					}
+					response := tc.responses[atomic.AddInt32(&polls, 1)-1]
+					if response != "" {
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(`{"error": "` + response + `"}`))
+						return
+					}
+					w.Write([]byte(`{"access_token": "access", "refresh_token": "refresh", "expires_in": 3600}`))
+				}
+			}))
+			defer server.Close()
+
+			restClient := NewRestClient(server.URL, "")
+			authorization, err := restClient.StartDeviceLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if authorization.UserCode != "ABCD-EFGH" {
+				t.Fatalf("Unexpected user code %q", authorization.UserCode)
+			}
+			session, err := restClient.WaitForDeviceLogin(authorization)
+			if err != tc.expected {
+				t.Fatalf("Expected %v, got %v", tc.expected, err)
+			}
+			if tc.expected == nil && session.RefreshToken != "refresh" {
+				t.Fatalf("Expected the refresh token, got %q", session.RefreshToken)
+			}
+			if int(polls) != len(tc.responses) {
+				t.Fatalf("Expected %d polls, got %d", len(tc.responses), polls)
+			}
+		})
+	}
+}
+
+func TestDeviceLoginServerErrors(t *testing.T) {
+	deviceIntervalUnit = time.Millisecond
+	defer func() { deviceIntervalUnit = time.Second }()
+
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	authorization := DeviceAuthorization{DeviceCode: "device", ExpiresIn: 200, Interval: 1}
+	_, err := restClient.WaitForDeviceLogin(authorization)
+	if err != ErrDeviceExpired {
+		t.Fatalf("Expected %v, got %v", ErrDeviceExpired, err)
+	}
+	// Backing off between 1ms and 4ms for 200ms keeps polling but
+	// never spins without waiting
+	if polls < 10 || polls > 200 {
+		t.Fatalf("Expected the polls to back off, got %d", polls)
+	}
+}
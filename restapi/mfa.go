@@ -0,0 +1,94 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/google/jsonapi"
+)
+
+//MFASetup What an authenticator app needs to generate codes for the account
+type MFASetup struct {
+	ID     string `jsonapi:"primary,mfa"`
+	Secret string `jsonapi:"attr,secret,omitempty"`
+	// URI is the otpauth:// URI authenticator apps scan from a QR code
+	URI  string `jsonapi:"attr,uri,omitempty"`
+	Code string `jsonapi:"attr,code,omitempty"`
+}
+
+//EnableMFA Starts enabling two-factor authentication. It stays off until
+//ConfirmMFA is called with a code generated from the returned secret.
+func (restClient *RestClient) EnableMFA() (MFASetup, error) {
+	return restClient.EnableMFAContext(context.Background())
+}
+
+//EnableMFAContext EnableMFA that gives up when ctx is done
+func (restClient *RestClient) EnableMFAContext(ctx context.Context) (MFASetup, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	setup := MFASetup{}
+	req, err := http.NewRequest("POST", restClient.URL+"/account/mfa", nil)
+	if err != nil {
+		return setup, errorCantConnectRestCall
+	}
+	resp, err := restClient.do(ctx, req)
+	if err != nil {
+		return setup, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		buf, _ := ioutil.ReadAll(resp.Body)
+		return setup, responseError(resp.StatusCode, buf)
+	}
+
+	err = jsonapi.UnmarshalPayload(resp.Body, &setup)
+	if err != nil {
+		return setup, errorUnableToParse
+	}
+	return setup, nil
+}
+
+//ConfirmMFA Turns on two-factor authentication once code proves the authenticator works
+func (restClient *RestClient) ConfirmMFA(code string) error {
+	return restClient.ConfirmMFAContext(context.Background(), code)
+}
+
+//ConfirmMFAContext ConfirmMFA that gives up when ctx is done
+func (restClient *RestClient) ConfirmMFAContext(ctx context.Context, code string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	return restClient.sendPayload(ctx, "PUT", "/account/mfa", &MFASetup{Code: code})
+}
+
+//DisableMFA Turns off two-factor authentication, it takes a current code
+func (restClient *RestClient) DisableMFA(code string) error {
+	return restClient.DisableMFAContext(context.Background(), code)
+}
+
+//DisableMFAContext DisableMFA that gives up when ctx is done
+func (restClient *RestClient) DisableMFAContext(ctx context.Context, code string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	return restClient.sendPayload(ctx, "DELETE", "/account/mfa", &MFASetup{Code: code})
+}
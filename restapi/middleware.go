@@ -0,0 +1,72 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+//Middleware Wraps a RoundTripper to add behaviour to every request
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+//RoundTripperFunc Lets a plain function be used as a RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+//RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+//Chain Wraps rt in every middleware, the first one ends up outermost
+func Chain(rt http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
+
+//Header Sets a header on every request
+func Header(key string, value string) Middleware {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		handler := WithHeader(rt)
+		handler.Set(key, value)
+		return handler
+	}
+}
+
+//RequestID Gives every request a random X-Request-Id unless it already has one,
+//so a request can be found in the server logs
+func RequestID() Middleware {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("X-Request-Id") != "" {
+				return rt.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set("X-Request-Id", randomID())
+			return rt.RoundTrip(req)
+		})
+	}
+}
+
+func randomID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
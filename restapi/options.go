@@ -0,0 +1,98 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//Option Configures a RestClient created by NewRestClient
+type Option func(*clientOptions)
+
+type clientOptions struct {
+	transport      *http.Transport
+	middlewares    []Middleware
+	userAgent      string
+	retries        int
+	tokenCache     TokenCache
+	requestTimeout time.Duration
+	overallTimeout time.Duration
+}
+
+//WithTimeouts request caps every single HTTP request, overall caps a whole API
+//call including token refreshes. Zero means no limit.
+func WithTimeouts(request time.Duration, overall time.Duration) Option {
+	return func(o *clientOptions) {
+		o.requestTimeout = request
+		o.overallTimeout = overall
+	}
+}
+
+//WithRetries Retries transient failures up to retries more times, zero disables retrying
+func WithRetries(retries int) Option {
+	return func(o *clientOptions) {
+		o.retries = retries
+	}
+}
+
+//WithConnectionPool Keeps up to maxIdle idle connections per host open for idleTimeout
+func WithConnectionPool(maxIdle int, idleTimeout time.Duration) Option {
+	return func(o *clientOptions) {
+		o.transport.MaxIdleConnsPerHost = maxIdle
+		o.transport.IdleConnTimeout = idleTimeout
+	}
+}
+
+//WithTLSConfig Uses config for every connection to the API
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *clientOptions) {
+		o.transport.TLSClientConfig = config
+	}
+}
+
+//WithUserAgent Sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(o *clientOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+//WithMiddleware Adds middleware around the transport, in the order given
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *clientOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+//WithProxy Picks the proxy for every request, by default the environment is used
+func WithProxy(proxy func(*url.URL) (*url.URL, error)) Option {
+	return func(o *clientOptions) {
+		o.transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+}
+
+//WithTokenCache Reuses access tokens from cache until shortly before they expire
+func WithTokenCache(cache TokenCache) Option {
+	return func(o *clientOptions) {
+		o.tokenCache = cache
+	}
+}
@@ -0,0 +1,109 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/google/jsonapi"
+)
+
+type passwordResetRequest struct {
+	ID       string `jsonapi:"primary,password_reset"`
+	Email    string `jsonapi:"attr,email,omitempty"`
+	Token    string `jsonapi:"attr,token,omitempty"`
+	Password string `jsonapi:"attr,password,omitempty"`
+}
+
+type passwordChangeRequest struct {
+	ID              string `jsonapi:"primary,password"`
+	CurrentPassword string `jsonapi:"attr,currentPassword"`
+	NewPassword     string `jsonapi:"attr,newPassword"`
+}
+
+//RequestPasswordReset Emails a password reset token to the account
+func (restClient *RestClient) RequestPasswordReset(email string) error {
+	return restClient.RequestPasswordResetContext(context.Background(), email)
+}
+
+//RequestPasswordResetContext RequestPasswordReset that gives up when ctx is done
+func (restClient *RestClient) RequestPasswordResetContext(ctx context.Context, email string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	request := passwordResetRequest{Email: email}
+	return restClient.sendPayload(withoutAuth(ctx), "POST", "/account/password_reset", &request)
+}
+
+//ResetPassword Sets a new password using the token from the reset email
+func (restClient *RestClient) ResetPassword(token string, password string) error {
+	return restClient.ResetPasswordContext(context.Background(), token, password)
+}
+
+//ResetPasswordContext ResetPassword that gives up when ctx is done
+func (restClient *RestClient) ResetPasswordContext(ctx context.Context, token string, password string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	request := passwordResetRequest{Token: token, Password: password}
+	return restClient.sendPayload(withoutAuth(ctx), "PUT", "/account/password_reset", &request)
+}
+
+//ChangePassword Changes the password of the logged in account
+func (restClient *RestClient) ChangePassword(currentPassword string, newPassword string) error {
+	return restClient.ChangePasswordContext(context.Background(), currentPassword, newPassword)
+}
+
+//ChangePasswordContext ChangePassword that gives up when ctx is done
+func (restClient *RestClient) ChangePasswordContext(ctx context.Context, currentPassword string, newPassword string) error {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	request := passwordChangeRequest{CurrentPassword: currentPassword, NewPassword: newPassword}
+	return restClient.sendPayload(ctx, "PATCH", "/account/password", &request)
+}
+
+// sendPayload sends a JSON:API document and only cares whether it was accepted
+func (restClient *RestClient) sendPayload(ctx context.Context, method string, path string, payload interface{}) error {
+	var outputBuffer bytes.Buffer
+	_ = bufio.NewWriter(&outputBuffer)
+	err := jsonapi.MarshalPayload(&outputBuffer, payload)
+	if err != nil {
+		return errorUnableToParse
+	}
+	req, err := http.NewRequest(method, restClient.URL+path, &outputBuffer)
+	if err != nil {
+		return errorCantConnectRestCall
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := restClient.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode > 399 {
+		return responseError(resp.StatusCode, body)
+	}
+	return nil
+}
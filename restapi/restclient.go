@@ -17,50 +17,127 @@
 package restapi
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
+	"time"
 )
 
 var apiVersion = "2019.7.18.1"
 
-//RestClient A stuct to hold persistent data that is used between rest calls
+//RestClient A stuct to hold persistent data that is used between rest calls.
+//Copies share the same tokens, so a refresh through one is seen by all of them.
 type RestClient struct {
-	URL          string
-	RefreshToken string
-	APIKey		 string
-	Client       http.Client
+	URL    string
+	Client http.Client
+	// Timeout caps a whole API call including token refreshes, zero means no limit
+	Timeout time.Duration
+	auth    *authTransport
 }
 
-//NewRestClient Use this method to create a new rest client so headers can be setup
-func NewRestClient(apiEndpoint string, refreshToken string) RestClient {
-	client := http.DefaultClient
-	rt := WithHeader(client.Transport)
-	rt.Set("Api-Version", apiVersion)
-	client.Transport = rt
+//NewRestClient Use this method to create a new rest client so headers can be setup.
+//Every client gets its own http.Client and transport, nothing global is changed.
+func NewRestClient(apiEndpoint string, refreshToken string, options ...Option) RestClient {
+	opts := clientOptions{
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
+		userAgent: "ulacli",
+		retries:   3,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	// Our own headers go on first so the caller's middleware sees them
+	middlewares := append([]Middleware{
+		Header("Api-Version", apiVersion),
+		Header("User-Agent", opts.userAgent),
+		RequestID(),
+		Retry(opts.retries),
+	}, opts.middlewares...)
+	auth := &authTransport{
+		url:            apiEndpoint,
+		rt:             Chain(opts.transport, middlewares...),
+		refreshToken:   refreshToken,
+		requestTimeout: opts.requestTimeout,
+		cache:          opts.tokenCache,
+	}
 	restAPI := RestClient{
-		URL:          apiEndpoint,
-		RefreshToken: refreshToken,
-		Client:       *client,
+		URL: apiEndpoint,
+		Client: http.Client{
+			Transport: auth,
+			Timeout:   opts.requestTimeout,
+		},
+		Timeout: opts.overallTimeout,
+		auth:    auth,
 	}
 	return restAPI
 }
 
+//SetRefreshToken set the refresh token used to get new access tokens
 func (restClient *RestClient) SetRefreshToken(refreshToken string) {
-	rt := WithHeader(nil)
-	rt.Set("Authorization", "Bearer "+restClient.RefreshToken)
-	restClient.Client.Transport = rt
+	restClient.auth.mu.Lock()
+	defer restClient.auth.mu.Unlock()
+	restClient.auth.refreshToken = refreshToken
 }
 
-
 //SetAPIKey set api key header
 func (restClient *RestClient) SetAPIKey(apiKey string) {
-	restClient.APIKey = apiKey
-	rt := WithHeader(nil)
-	rt.Set("Authorization", "Bearer "+apiKey)
-	restClient.Client.Transport = rt
+	restClient.auth.mu.Lock()
+	defer restClient.auth.mu.Unlock()
+	restClient.auth.accessToken = apiKey
+	restClient.auth.expiresAt = time.Time{}
+}
+
+//SetTimeouts request caps every single HTTP request, overall caps a whole API
+//call including token refreshes. Zero means no limit.
+func (restClient *RestClient) SetTimeouts(request time.Duration, overall time.Duration) {
+	restClient.Client.Timeout = request
+	restClient.Timeout = overall
+	restClient.auth.mu.Lock()
+	defer restClient.auth.mu.Unlock()
+	restClient.auth.requestTimeout = request
+}
+
+func (restClient *RestClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if restClient.Timeout > 0 {
+		return context.WithTimeout(ctx, restClient.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (restClient *RestClient) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if ctx.Value(noAuthKey{}) == nil {
+		err := restClient.auth.ensureToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	resp, err := restClient.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, connectionError(ctx, err)
+	}
+	return resp, nil
+}
+
+// connectionError keeps cancellation visible to callers so Ctrl-C isn't reported as a network problem
+func connectionError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, errorCertificateMismatch) {
+		return errorCertificateMismatch
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return errorTimeout
+	}
+	return errorCantConnectRestCall
 }
 
 //RoundTrip middleware that sets the headers of each request
 func (h ClientHandler) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
 	for k, v := range h.Header {
 		req.Header[k] = v
 	}
@@ -0,0 +1,120 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cases := []struct {
+		name     string
+		request  time.Duration
+		overall  time.Duration
+		cancel   bool
+		expected error
+	}{
+		{"Request timeout", 50 * time.Millisecond, 0, false, errorTimeout},
+		{"Overall deadline", 0, 50 * time.Millisecond, false, context.DeadlineExceeded},
+		{"Cancelled", 0, 0, true, context.Canceled},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			restClient := NewRestClient(server.URL, "")
+			restClient.SetTimeouts(tc.request, tc.overall)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+			err := restClient.DeleteBoxAPIContext(ctx, "1")
+			if err != tc.expected {
+				t.Fatalf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestHeadersSurviveAuth(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	defaultTransport := http.DefaultClient.Transport
+	restClient := NewRestClient(server.URL, "", WithUserAgent("ulacli/test"))
+	restClient.SetAPIKey("token")
+	restClient.SetRefreshToken("refresh")
+	if http.DefaultClient.Transport != defaultTransport {
+		t.Fatal("NewRestClient changed http.DefaultClient")
+	}
+
+	err := restClient.DeleteBoxAPI("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"Api-Version":   apiVersion,
+		"User-Agent":    "ulacli/test",
+		"Authorization": "Bearer token",
+	}
+	for key, value := range expected {
+		if headers.Get(key) != value {
+			t.Errorf("Expected %s %q, got %q", key, value, headers.Get(key))
+		}
+	}
+	if headers.Get("X-Request-Id") == "" {
+		t.Error("Expected a request id")
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(rt http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return rt.RoundTrip(req)
+			})
+		}
+	}
+	rt := Chain(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		order = append(order, "transport")
+		return &http.Response{StatusCode: 204, Body: http.NoBody}, nil
+	}), mark("first"), mark("second"))
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	_, _ = rt.RoundTrip(req)
+	if len(order) != 3 || order[0] != "first" || order[1] != "second" || order[2] != "transport" {
+		t.Fatalf("Unexpected order %v", order)
+	}
+}
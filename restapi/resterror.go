@@ -17,14 +17,127 @@
 package restapi
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 )
 
 var errorCantConnectRestCall = errors.New("problem contacting the server")
 var errorUnableToParse = errors.New("can't parse the json response")
 var errorUnownedBox = errors.New("you do not own this box")
 var errorUnableToDelete = errors.New("failed to delete")
+var errorNoRefreshToken = errors.New("not logged in")
+var errorTimeout = errors.New("the server took too long to respond")
+var errorCertificateMismatch = errors.New("the server certificate does not match the pinned fingerprint")
+
+// Sentinel errors to match an *APIError against with errors.Is
+var (
+	//ErrEmailUnconfirmed The account's email address has not been confirmed yet
+	ErrEmailUnconfirmed = errors.New("email address not confirmed")
+	//ErrVersionMismatch This version of ulacli is too old for the API
+	ErrVersionMismatch = errors.New("client version is incompatible with the api")
+	//ErrUnauthorized The credentials or token were rejected
+	ErrUnauthorized = errors.New("authentication failed")
+	//ErrQuotaExceeded The account has no room for another box
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	//ErrNotFound The requested resource does not exist
+	ErrNotFound = errors.New("not found")
+	//ErrMFARequired The account has two-factor authentication and the login needs a code
+	ErrMFARequired = errors.New("two-factor authentication code required")
+)
+
+// Older API versions only tell these apart by the detail message
+const (
+	emailUnconfirmedDetail = "Must confirm email before you use the service"
+	versionMismatchDetail  = "incompatiable with the api"
+)
+
+//APIError An error response from the API, keeping the HTTP status it came with.
+//Source is the JSON pointer to the part of the request that caused it, if any.
+type APIError struct {
+	StatusCode int
+	Title      string
+	Code       string
+	Detail     string
+	Source     string
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	if e.Title != "" {
+		return e.Title
+	}
+	return fmt.Sprintf("request failed with status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+//Is Lets errors.Is match the error against the sentinel errors of this package
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrEmailUnconfirmed:
+		return e.Code == "email_unconfirmed" || e.Detail == emailUnconfirmedDetail
+	case ErrVersionMismatch:
+		return e.Code == "version_mismatch" || strings.Contains(e.Detail, versionMismatchDetail)
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrQuotaExceeded:
+		return e.Code == "quota_exceeded" || e.StatusCode == http.StatusPaymentRequired
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrMFARequired:
+		return e.Code == "mfa_required"
+	}
+	return false
+}
+
+//APIErrors Several errors returned in one response
+type APIErrors []*APIError
+
+func (e APIErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, apiError := range e {
+		messages = append(messages, apiError.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+//Is Matches if any of the errors matches target
+func (e APIErrors) Is(target error) bool {
+	for _, apiError := range e {
+		if errors.Is(apiError, target) {
+			return true
+		}
+	}
+	return false
+}
+
+//As Finds the first of the errors that can be assigned to target
+func (e APIErrors) As(target interface{}) bool {
+	for _, apiError := range e {
+		if errors.As(apiError, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorDocument is the error document from the JSON:API spec, https://jsonapi.org/format/#errors
+type errorDocument struct {
+	Errors []struct {
+		Status string `json:"status"`
+		Code   string `json:"code"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+		Source struct {
+			Pointer   string `json:"pointer"`
+			Parameter string `json:"parameter"`
+		} `json:"source"`
+	} `json:"errors"`
+}
 
 //ResponseError JSONapi response error
 type ResponseError struct {
@@ -33,6 +146,7 @@ type ResponseError struct {
 		Attributes struct {
 			Title  string `json:"title"`
 			Status string `json:"status"`
+			Code   string `json:"code"`
 			Detail string `json:"detail"`
 		} `json:"attributes"`
 		ID string `json:"id"`
@@ -40,5 +154,54 @@ type ResponseError struct {
 }
 
 func (e *ResponseError) Error() string {
-	return fmt.Sprintf(e.Data.Attributes.Detail)
+	return e.Data.Attributes.Detail
+}
+
+// responseError turns the body of an error response into an error. It understands
+// both the spec's top level errors array and the single error object in data that
+// older API versions send. Anything else, like a proxy's HTML page, gets a plain
+// error with the status code.
+func responseError(statusCode int, body []byte) error {
+	document := errorDocument{}
+	if json.Unmarshal(body, &document) == nil && len(document.Errors) > 0 {
+		apiErrors := make(APIErrors, 0, len(document.Errors))
+		for _, e := range document.Errors {
+			status, err := strconv.Atoi(e.Status)
+			if err != nil {
+				status = statusCode
+			}
+			source := e.Source.Pointer
+			if source == "" {
+				source = e.Source.Parameter
+			}
+			apiErrors = append(apiErrors, &APIError{
+				StatusCode: status,
+				Title:      e.Title,
+				Code:       e.Code,
+				Detail:     e.Detail,
+				Source:     source,
+			})
+		}
+		if len(apiErrors) == 1 {
+			return apiErrors[0]
+		}
+		return apiErrors
+	}
+
+	errorBody := ResponseError{}
+	attributes := &errorBody.Data.Attributes
+	err := json.Unmarshal(body, &errorBody)
+	if err != nil || (attributes.Title == "" && attributes.Detail == "" && attributes.Code == "") {
+		return &APIError{
+			StatusCode: statusCode,
+			Title:      http.StatusText(statusCode),
+			Detail:     fmt.Sprintf("unexpected response from the server (%d %s)", statusCode, http.StatusText(statusCode)),
+		}
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Title:      attributes.Title,
+		Code:       attributes.Code,
+		Detail:     attributes.Detail,
+	}
 }
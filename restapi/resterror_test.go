@@ -0,0 +1,101 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+	}{
+		{"Unconfirmed email", 403, `{"data": {"attributes": {"detail": "Must confirm email before you use the service"}}}`, ErrEmailUnconfirmed},
+		{"Unconfirmed email code", 403, `{"data": {"attributes": {"code": "email_unconfirmed"}}}`, ErrEmailUnconfirmed},
+		{"Version mismatch", 400, `{"data": {"attributes": {"detail": "Your client is incompatiable with the api"}}}`, ErrVersionMismatch},
+		{"Unauthorized", 401, `{"data": {"attributes": {"detail": "Bad credentials"}}}`, ErrUnauthorized},
+		{"Quota", 402, `{"data": {"attributes": {"detail": "Too many boxes"}}}`, ErrQuotaExceeded},
+		{"Not found", 404, `{"data": {"attributes": {"detail": "No such box"}}}`, ErrNotFound},
+	}
+	sentinels := []error{ErrEmailUnconfirmed, ErrVersionMismatch, ErrUnauthorized, ErrQuotaExceeded, ErrNotFound}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", responseError(tc.status, []byte(tc.body)))
+			for _, sentinel := range sentinels {
+				if errors.Is(err, sentinel) != (sentinel == tc.expected) {
+					t.Fatalf("errors.Is(%q) should be %v", sentinel, sentinel == tc.expected)
+				}
+			}
+			var apiError *APIError
+			if !errors.As(err, &apiError) || apiError.StatusCode != tc.status {
+				t.Fatal("Expected an *APIError with the HTTP status")
+			}
+		})
+	}
+}
+
+func TestResponseErrorShapes(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+		count    int
+	}{
+		{"Legacy data object", 400, `{"data": {"attributes": {"title": "Bad", "detail": "Missing email"}}}`, "Missing email", 1},
+		{"Spec errors array", 422, `{"errors": [{"status": "422", "title": "Invalid", "detail": "Email is taken", "source": {"pointer": "/data/attributes/email"}}]}`, "Email is taken", 1},
+		{"Multiple errors", 422, `{"errors": [{"detail": "Email is taken"}, {"detail": "Password too short"}]}`, "Email is taken; Password too short", 2},
+		{"HTML page", 502, `<html><body>Bad Gateway</body></html>`, "unexpected response from the server (502 Bad Gateway)", 1},
+		{"Empty body", 500, ``, "unexpected response from the server (500 Internal Server Error)", 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := responseError(tc.status, []byte(tc.body))
+			if err.Error() != tc.expected {
+				t.Fatalf("Expected %q, got %q", tc.expected, err.Error())
+			}
+			count := 1
+			if apiErrors, ok := err.(APIErrors); ok {
+				count = len(apiErrors)
+			}
+			if count != tc.count {
+				t.Fatalf("Expected %d errors, got %d", tc.count, count)
+			}
+			var apiError *APIError
+			if !errors.As(err, &apiError) || apiError.StatusCode != tc.status {
+				t.Fatal("Expected an *APIError with the HTTP status")
+			}
+		})
+	}
+
+	err := responseError(422, []byte(`{"errors": [{"status": "422", "detail": "Bad"}, {"status": "404", "detail": "Gone"}]}`))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("Any of several errors should match errors.Is")
+	}
+	var apiError *APIError
+	errors.As(err, &apiError)
+	if apiError.Source != "" || apiError.Detail != "Bad" {
+		t.Fatal("errors.As should find the first error")
+	}
+}
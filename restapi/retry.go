@@ -0,0 +1,146 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cypherpunkarmory/ulacli/backoff"
+)
+
+// Retrying gives up once this much time has been spent waiting
+const maxRetryElapsed = 30 * time.Second
+
+// A Retry-After longer than this isn't worth waiting for in a CLI
+const maxRetryAfter = time.Minute
+
+//Retry Retries requests that failed for a transient reason up to attempts
+//more times. Rate limited requests are always retried, other failures only when
+//the method is idempotent or the request carries an Idempotency-Key.
+func Retry(attempts int) Middleware {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			exponentialBackoff := backoff.NewExponentialBackOff()
+			exponentialBackoff.InitialInterval = 500 * time.Millisecond
+			exponentialBackoff.MaxElapsedTime = maxRetryElapsed
+			exponentialBackoff.Reset()
+
+			for attempt := 0; ; attempt++ {
+				resp, err := rt.RoundTrip(req)
+				if attempt >= attempts || !shouldRetry(req, resp, err) {
+					return resp, err
+				}
+
+				wait := exponentialBackoff.NextBackOff()
+				if resp != nil {
+					if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+						wait = retryAfter
+					}
+				}
+				if wait == backoff.Stop || wait > maxRetryAfter || !canReplay(req) {
+					return resp, err
+				}
+				if resp != nil {
+					// Drain so the connection can be reused
+					_, _ = io.Copy(ioutil.Discard, resp.Body)
+					resp.Body.Close()
+				}
+
+				err = sleepContext(req.Context(), wait)
+				if err != nil {
+					return nil, err
+				}
+
+				if req.GetBody != nil {
+					req = req.Clone(req.Context())
+					req.Body, err = req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		})
+	}
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		// The server turned the request away without acting on it
+		return true
+	}
+	if !idempotent(req) {
+		return false
+	}
+	if err != nil {
+		return !certificateError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// certificateError is true for failures that will fail the same way every time
+func certificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.Is(err, errorCertificateMismatch) ||
+		errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostname) ||
+		errors.As(err, &invalid)
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func canReplay(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// parseRetryAfter understands both forms from RFC 7231, delay-seconds and an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
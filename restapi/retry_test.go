@@ -0,0 +1,120 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	cases := []struct {
+		name     string
+		method   string
+		statuses []int
+		expected []int
+	}{
+		{"Rate limited", "POST", []int{429, 201}, []int{429, 201}},
+		{"Unavailable GET", "GET", []int{503, 502, 200}, []int{503, 502, 200}},
+		{"Unavailable POST", "POST", []int{503, 201}, []int{503}},
+		{"Client error", "GET", []int{400, 200}, []int{400}},
+		{"Gives up", "DELETE", []int{503, 503, 503, 503, 204}, []int{503, 503, 503, 503}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var seen []int
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				body, _ := ioutil.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				status := tc.statuses[len(seen)]
+				seen = append(seen, status)
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			client := http.Client{Transport: Chain(http.DefaultTransport, Retry(3))}
+			req, _ := http.NewRequest(tc.method, server.URL, nil)
+			if tc.method == "POST" {
+				req, _ = http.NewRequest(tc.method, server.URL, strings.NewReader("payload"))
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(seen) != len(tc.expected) || resp.StatusCode != tc.expected[len(tc.expected)-1] {
+				t.Fatalf("Expected %v, got %v ending in %d", tc.expected, seen, resp.StatusCode)
+			}
+			for _, body := range bodies {
+				if tc.method == "POST" && body != "payload" {
+					t.Fatalf("Retried request lost its body: %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryIdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(503)
+			return
+		}
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	restClient.SetAPIKey("token")
+	_, _ = restClient.CreateBoxAPI("key", "image", 0)
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("Expected one retry with the same key, got %v", keys)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	wait, ok := parseRetryAfter("7")
+	if !ok || wait != 7*time.Second {
+		t.Fatalf("Expected 7s, got %v", wait)
+	}
+	wait, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if !ok || wait < 59*time.Minute {
+		t.Fatalf("Expected about an hour, got %v", wait)
+	}
+	_, ok = parseRetryAfter("soon")
+	if ok {
+		t.Fatal("Expected an invalid Retry-After to be ignored")
+	}
+}
@@ -0,0 +1,103 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+//TLSOptions Settings for talking to a self-hosted API endpoint
+type TLSOptions struct {
+	// CABundle is a PEM file of extra certificate authorities to trust
+	CABundle string
+	// ClientCert and ClientKey are PEM files used for mutual TLS
+	ClientCert string
+	ClientKey  string
+	// PinnedCert is the SHA-256 fingerprint of the server's leaf certificate
+	PinnedCert string
+}
+
+//TLSConfig Builds a tls.Config from options, nil when nothing was configured
+func TLSConfig(options TLSOptions) (*tls.Config, error) {
+	if options == (TLSOptions{}) {
+		return nil, nil
+	}
+	config := &tls.Config{}
+
+	if options.CABundle != "" {
+		pem, err := ioutil.ReadFile(options.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", options.CABundle)
+		}
+		config.RootCAs = pool
+	}
+
+	if options.ClientCert != "" || options.ClientKey != "" {
+		if options.ClientCert == "" || options.ClientKey == "" {
+			return nil, errors.New("a client certificate needs both a certificate and a key")
+		}
+		cert, err := tls.LoadX509KeyPair(options.ClientCert, options.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if options.PinnedCert != "" {
+		pin, err := parseFingerprint(options.PinnedCert)
+		if err != nil {
+			return nil, err
+		}
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errorCertificateMismatch
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(sum[:], pin) {
+				return errorCertificateMismatch
+			}
+			return nil
+		}
+	}
+	return config, nil
+}
+
+// parseFingerprint accepts the hex forms openssl and browsers print, with or without colons
+func parseFingerprint(fingerprint string) ([]byte, error) {
+	clean := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(fingerprint)), "sha256:")
+	clean = strings.Replace(clean, ":", "", -1)
+	pin, err := hex.DecodeString(clean)
+	if err != nil || len(pin) != sha256.Size {
+		return nil, fmt.Errorf("%q is not a SHA-256 fingerprint", fingerprint)
+	}
+	return pin, nil
+}
@@ -0,0 +1,96 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "ulacli-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caBundle := filepath.Join(dir, "ca.pem")
+	raw := server.Certificate().Raw
+	err = ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	cases := []struct {
+		name     string
+		options  TLSOptions
+		expected error
+	}{
+		{"Untrusted", TLSOptions{PinnedCert: fingerprint}, errorCantConnectRestCall},
+		{"CA bundle", TLSOptions{CABundle: caBundle}, nil},
+		{"Pinned", TLSOptions{CABundle: caBundle, PinnedCert: "SHA256:" + fingerprint}, nil},
+		{"Pin mismatch", TLSOptions{CABundle: caBundle, PinnedCert: hex.EncodeToString(make([]byte, 32))}, errorCertificateMismatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := TLSConfig(tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restClient := NewRestClient(server.URL, "", WithTLSConfig(config))
+			restClient.SetAPIKey("token")
+			err = restClient.DeleteBoxAPI("1")
+			if err != tc.expected {
+				t.Fatalf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		options TLSOptions
+	}{
+		{"Missing bundle", TLSOptions{CABundle: "/does/not/exist.pem"}},
+		{"Cert without key", TLSOptions{ClientCert: "client.pem"}},
+		{"Bad fingerprint", TLSOptions{PinnedCert: "abc"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := TLSConfig(tc.options)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Tokens this close to expiry are refreshed before use instead of risking a 401
+const tokenExpirySkew = time.Minute
+
+//CachedToken An access token and when it stops working
+type CachedToken struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+//Valid Reports whether the token can still be used for a while
+func (t CachedToken) Valid() bool {
+	return t.AccessToken != "" && time.Until(t.ExpiresAt) > tokenExpirySkew
+}
+
+//TokenCache Keeps access tokens between runs so every command doesn't have to start a new session
+type TokenCache interface {
+	Get(key string) (CachedToken, bool)
+	Set(key string, token CachedToken) error
+	Delete(key string) error
+}
+
+//FileTokenCache A TokenCache stored as JSON in a file only the user can read
+type FileTokenCache struct {
+	Path string
+	mu   sync.Mutex
+}
+
+//NewFileTokenCache Creates a cache stored at path
+func NewFileTokenCache(path string) *FileTokenCache {
+	return &FileTokenCache{Path: path}
+}
+
+//Get Returns the token stored under key if it is still valid
+func (c *FileTokenCache) Get(key string) (CachedToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	token, ok := c.read()[key]
+	if !ok || !token.Valid() {
+		return CachedToken{}, false
+	}
+	return token, true
+}
+
+//Set Stores token under key, dropping any tokens that have expired
+func (c *FileTokenCache) Set(key string, token CachedToken) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tokens := c.read()
+	for k, t := range tokens {
+		if !t.Valid() {
+			delete(tokens, k)
+		}
+	}
+	tokens[key] = token
+	return c.write(tokens)
+}
+
+//Delete Forgets the token stored under key
+func (c *FileTokenCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tokens := c.read()
+	if _, ok := tokens[key]; !ok {
+		return nil
+	}
+	delete(tokens, key)
+	return c.write(tokens)
+}
+
+// read treats a missing or corrupt file as an empty cache, it only costs a new session
+func (c *FileTokenCache) read() map[string]CachedToken {
+	tokens := map[string]CachedToken{}
+	buf, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return tokens
+	}
+	_ = json.Unmarshal(buf, &tokens)
+	return tokens
+}
+
+func (c *FileTokenCache) write(tokens map[string]CachedToken) error {
+	buf, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(c.Path), 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.Path), filepath.Base(c.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	// TempFile already creates the file 0600, tokens must never be readable by others
+	_, err = tmp.Write(buf)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.Path)
+}
+
+// tokenKey ties a cached token to the endpoint and refresh token it came from,
+// without storing the refresh token itself
+func tokenKey(endpoint string, refreshToken string) string {
+	sum := sha256.Sum256([]byte(endpoint + "\x00" + refreshToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,194 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// Bodies longer than this are cut short in the trace
+const maxTraceBody = 16 * 1024
+
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// JSON and form keys whose values never belong in a bug report, compared in lower case
+var redactedFields = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"currentpassword":  true,
+	"new_password":     true,
+	"newpassword":      true,
+	"access_token":     true,
+	"accesstoken":      true,
+	"refresh_token":    true,
+	"refreshtoken":     true,
+	"device_code":      true,
+	"token":            true,
+	"otp":              true,
+	"secret":           true,
+	"apikey":           true,
+}
+
+// Paths whose bodies are left out entirely. The two-factor secret comes back as an
+// otpauth URI and the codes go out as plain attributes, neither of which a list
+// of field names catches without also hiding error codes.
+var redactedBodies = []string{"/account/mfa"}
+
+//Trace Writes every request and response to w with credentials redacted, so the
+//transcript can be attached to a bug report
+func Trace(w io.Writer) Middleware {
+	var mu sync.Mutex
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, "--> %s %s\n", req.Method, req.URL)
+			writeHeaders(&buf, req.Header)
+			if req.Body != nil && req.Body != http.NoBody {
+				body, err := ioutil.ReadAll(req.Body)
+				req.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Body = ioutil.NopCloser(bytes.NewReader(body))
+				writeBody(&buf, req.URL.Path, req.Header, body)
+			}
+
+			started := time.Now()
+			resp, err := rt.RoundTrip(req)
+			elapsed := time.Since(started).Truncate(time.Millisecond)
+			if err != nil {
+				fmt.Fprintf(&buf, "<-- %s %s failed: %s (%s)\n\n", req.Method, req.URL, err, elapsed)
+			} else {
+				fmt.Fprintf(&buf, "<-- %s %s (%s)\n", resp.Status, req.URL, elapsed)
+				writeHeaders(&buf, resp.Header)
+				body, readErr := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+				writeBody(&buf, req.URL.Path, resp.Header, body)
+				if readErr != nil {
+					fmt.Fprintf(&buf, "(reading the body failed: %s)\n", readErr)
+				}
+				buf.WriteString("\n")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = w.Write(buf.Bytes())
+			return resp, err
+		})
+	}
+}
+
+func writeHeaders(buf *bytes.Buffer, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := strings.Join(header[key], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(key)] {
+			value = redacted
+		}
+		fmt.Fprintf(buf, "%s: %s\n", key, value)
+	}
+}
+
+func writeBody(buf *bytes.Buffer, path string, header http.Header, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	buf.WriteString("\n")
+	for _, p := range redactedBodies {
+		if strings.HasSuffix(path, p) {
+			fmt.Fprintf(buf, "(%d bytes left out, they hold two-factor authentication secrets)\n", len(body))
+			return
+		}
+	}
+	var document interface{}
+	if mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			body = []byte(redactForm(form))
+		}
+	} else if json.Unmarshal(body, &document) == nil {
+		body, _ = json.MarshalIndent(redactJSON(document), "", "  ")
+	}
+	if len(body) > maxTraceBody {
+		fmt.Fprintf(buf, "%s\n(%d more bytes)\n", body[:maxTraceBody], len(body)-maxTraceBody)
+		return
+	}
+	buf.Write(body)
+	buf.WriteString("\n")
+}
+
+// redactForm encodes form like url.Values.Encode but leaves the redaction marker readable
+func redactForm(form url.Values) string {
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		for _, value := range form[key] {
+			if redactedFields[strings.ToLower(key)] {
+				value = redacted
+			} else {
+				value = url.QueryEscape(value)
+			}
+			pairs = append(pairs, url.QueryEscape(key)+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedFields[strings.ToLower(key)] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactJSON(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,116 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceRedactsForm(t *testing.T) {
+	deviceIntervalUnit = time.Millisecond
+	defer func() { deviceIntervalUnit = time.Second }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token": "access-secret", "refresh_token": "refresh-secret", "expires_in": 3600}`))
+	}))
+	defer server.Close()
+
+	var trace bytes.Buffer
+	restClient := NewRestClient(server.URL, "", WithMiddleware(Trace(&trace)))
+	_, err := restClient.WaitForDeviceLogin(DeviceAuthorization{DeviceCode: "device-secret", Interval: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := trace.String()
+	for _, secret := range []string{"device-secret", "access-secret", "refresh-secret"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Trace leaked %q:\n%s", secret, output)
+		}
+	}
+	if !strings.Contains(output, "client_id=ulacli&device_code=[REDACTED]&grant_type=") {
+		t.Errorf("Trace is missing the redacted form:\n%s", output)
+	}
+}
+
+func TestTraceRedactsMFA(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Write([]byte(`{"data": {"type": "mfa", "id": "1", "attributes": {"secret": "JBSWY3DPEHPK3PXP", "uri": "otpauth://totp/ulacli:me?secret=JBSWY3DPEHPK3PXP&issuer=ulacli"}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var trace bytes.Buffer
+	restClient := NewRestClient(server.URL, "", WithMiddleware(Trace(&trace)))
+	restClient.SetAPIKey("access")
+	setup, err := restClient.EnableMFA()
+	if err != nil || setup.Secret != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("The trace must not change the response, got %+v %v", setup, err)
+	}
+	_ = restClient.ConfirmMFA("482915")
+	_ = restClient.DisableMFA("604113")
+
+	output := trace.String()
+	for _, secret := range []string{"JBSWY3DPEHPK3PXP", "otpauth", "482915", "604113"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Trace leaked %q:\n%s", secret, output)
+		}
+	}
+	for _, expected := range []string{"--> POST " + server.URL + "/account/mfa", "--> PUT " + server.URL + "/account/mfa", "--> DELETE " + server.URL + "/account/mfa", "bytes left out"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Trace is missing %q:\n%s", expected, output)
+		}
+	}
+}
+
+func TestTraceRedacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"secret-access","refresh_token":"secret-refresh","token_type":"bearer"}`))
+	}))
+	defer server.Close()
+
+	var trace bytes.Buffer
+	restClient := NewRestClient(server.URL, "", WithMiddleware(Trace(&trace)))
+	_, err := restClient.Login("me@example.com", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = restClient.DeleteBoxAPI("1")
+
+	output := trace.String()
+	for _, secret := range []string{"hunter2", "secret-access", "secret-refresh", "Bearer"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Trace leaked %q:\n%s", secret, output)
+		}
+	}
+	for _, expected := range []string{"--> POST " + server.URL + "/login", "<-- 200 OK", "me@example.com", "Authorization: [REDACTED]", "bearer"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Trace is missing %q:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package restapi
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/jsonapi"
+)
+
+//Usage JSONAPI response of the box time used in a billing period
+type Usage struct {
+	ID       string     `jsonapi:"primary,usage"`
+	Period   string     `jsonapi:"attr,period,omitempty"`
+	StartsAt *time.Time `jsonapi:"attr,startsAt,iso8601,omitempty"`
+	EndsAt   *time.Time `jsonapi:"attr,endsAt,iso8601,omitempty"`
+	BoxHours float64    `jsonapi:"attr,boxHours,omitempty"`
+	// Limits of zero mean the plan has no limit
+	BoxHourLimit float64      `jsonapi:"attr,boxHourLimit,omitempty"`
+	RunningBoxes int          `jsonapi:"attr,runningBoxes,omitempty"`
+	BoxLimit     int          `jsonapi:"attr,boxLimit,omitempty"`
+	Images       []ImageUsage `jsonapi:"attr,images,omitempty"`
+}
+
+//ImageUsage Box time used by one image
+type ImageUsage struct {
+	Image    string  `jsonapi:"attr,image"`
+	Boxes    int     `jsonapi:"attr,boxes,omitempty"`
+	BoxHours float64 `jsonapi:"attr,boxHours,omitempty"`
+}
+
+//Usage fetches the box time used in the current period, which is one of day, week or month
+func (restClient *RestClient) Usage(period string) (Usage, error) {
+	return restClient.UsageContext(context.Background(), period)
+}
+
+//UsageContext Usage that gives up when ctx is done
+func (restClient *RestClient) UsageContext(ctx context.Context, period string) (Usage, error) {
+	ctx, cancel := restClient.withTimeout(ctx)
+	defer cancel()
+
+	usage := Usage{}
+	req, err := http.NewRequest("GET", restClient.URL+"/account/usage?period="+url.QueryEscape(period), nil)
+	if err != nil {
+		return usage, errorCantConnectRestCall
+	}
+	resp, err := restClient.do(ctx, req)
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 399 {
+		buf, _ := ioutil.ReadAll(resp.Body)
+		return usage, responseError(resp.StatusCode, buf)
+	}
+
+	err = jsonapi.UnmarshalPayload(resp.Body, &usage)
+	if err != nil {
+		return usage, errorUnableToParse
+	}
+	return usage, nil
+}
+
+//WouldExceed reports whether starting one more box goes over the running box
+//limit or the box hours of the period are used up
+func (usage Usage) WouldExceed() bool {
+	if usage.BoxLimit > 0 && usage.RunningBoxes+1 > usage.BoxLimit {
+		return true
+	}
+	return usage.BoxHourLimit > 0 && usage.BoxHours >= usage.BoxHourLimit
+}
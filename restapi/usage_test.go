@@ -0,0 +1,63 @@
+// Userland Cloud CLI
+// Copyright (C) 2018-2019  Orb.House, LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// +build all unit
+
+package restapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/account/usage" || r.URL.Query().Get("period") != "week" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data": {"type": "usage", "id": "1", "attributes": {"period": "week", "boxHours": 12.5, "boxHourLimit": 100, "runningBoxes": 2, "boxLimit": 2,
+			"images": [{"image": "ubuntu", "boxes": 3, "boxHours": 10}, {"image": "kali", "boxes": 1, "boxHours": 2.5}]}}}`))
+	}))
+	defer server.Close()
+
+	restClient := NewRestClient(server.URL, "")
+	restClient.SetAPIKey("access")
+	usage, err := restClient.Usage("week")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.BoxHours != 12.5 || usage.RunningBoxes != 2 || usage.BoxLimit != 2 {
+		t.Fatalf("Unexpected usage %+v", usage)
+	}
+	expected := []ImageUsage{{"ubuntu", 3, 10}, {"kali", 1, 2.5}}
+	if len(usage.Images) != len(expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, usage.Images)
+	}
+	for i := range expected {
+		if usage.Images[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], usage.Images[i])
+		}
+	}
+	if !usage.WouldExceed() {
+		t.Error("A third box should exceed a limit of two")
+	}
+	usage.BoxLimit = 0
+	if usage.WouldExceed() {
+		t.Error("12.5 of 100 box hours should leave room")
+	}
+}